@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+)
+
+func printSecretAccessHelp() {
+	fmt.Println("List the workloads that consume a Secret and the ServiceAccounts that could read it via RBAC")
+	fmt.Println("\nUsage:")
+	fmt.Println("  k8s-microlens secret-access <name> [flags]")
+	fmt.Println("\nFlags:")
+	fmt.Println("  -n, --namespace string     Namespace of the Secret (default \"default\")")
+	fmt.Println("\nExamples:")
+	fmt.Println("  # Show everything that can read the db-credentials Secret")
+	fmt.Println("  k8s-microlens secret-access db-credentials -n payments")
+}
+
+// runSecretAccess implements the secret-access subcommand and returns the process exit code
+func runSecretAccess(args []string) int {
+	flags := flag.NewFlagSet("secret-access", flag.ExitOnError)
+	var (
+		namespace = flags.String("n", "default", "Namespace of the Secret")
+		help      = flags.Bool("h", false, "Show help message")
+	)
+	flags.StringVar(namespace, "namespace", "default", "Namespace of the Secret")
+	flags.BoolVar(help, "help", false, "Show help message")
+
+	// Accept flags on either side of the Secret name
+	flags.Parse(args)
+	name := flags.Arg(0)
+	if flags.NArg() > 0 {
+		flags.Parse(flags.Args()[1:])
+	}
+
+	if *help {
+		printSecretAccessHelp()
+		return 0
+	}
+	if name == "" || flags.NArg() > 0 {
+		printSecretAccessHelp()
+		return 2
+	}
+
+	rm, err := NewResourceMapper()
+	if err != nil {
+		fmt.Printf("%sError initializing resource mapper: %v%s\n", common.ColorRed, err, common.ColorReset)
+		return 2
+	}
+
+	if err := rm.processor.ShowSecretAccess(*namespace, name); err != nil {
+		fmt.Printf("%sError checking access to secret %s: %v%s\n", common.ColorRed, name, err, common.ColorReset)
+		return 2
+	}
+	return 0
+}
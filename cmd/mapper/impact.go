@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+)
+
+func printImpactHelp() {
+	fmt.Println("List the workloads that need a restart to pick up a change to a ConfigMap or Secret")
+	fmt.Println("\nUsage:")
+	fmt.Println("  k8s-microlens impact configmap/<name>|secret/<name> [flags]")
+	fmt.Println("\nFlags:")
+	fmt.Println("  -n, --namespace string     Namespace of the object (default \"default\")")
+	fmt.Println("      --restart-commands     Print kubectl rollout restart commands for affected workloads")
+	fmt.Println("\nExamples:")
+	fmt.Println("  # Check what a change to the app-config ConfigMap affects")
+	fmt.Println("  k8s-microlens impact configmap/app-config -n shop")
+	fmt.Println("\n  # Rotate a Secret and print the restarts it needs")
+	fmt.Println("  k8s-microlens impact secret/db-credentials -n payments --restart-commands")
+}
+
+// parseImpactTarget splits a kind/name argument into the object kind and name
+func parseImpactTarget(target string) (string, string, bool) {
+	kind, name, ok := strings.Cut(target, "/")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	switch strings.ToLower(kind) {
+	case "configmap", "configmaps", "cm":
+		return "ConfigMap", name, true
+	case "secret", "secrets":
+		return "Secret", name, true
+	}
+	return "", "", false
+}
+
+// runImpact implements the impact subcommand and returns the process exit code
+func runImpact(args []string) int {
+	flags := flag.NewFlagSet("impact", flag.ExitOnError)
+	var (
+		namespace = flags.String("n", "default", "Namespace of the object")
+		commands  = flags.Bool("restart-commands", false, "Print kubectl rollout restart commands")
+		help      = flags.Bool("h", false, "Show help message")
+	)
+	flags.StringVar(namespace, "namespace", "default", "Namespace of the object")
+	flags.BoolVar(help, "help", false, "Show help message")
+
+	// Accept flags on either side of the target
+	flags.Parse(args)
+	target := flags.Arg(0)
+	if flags.NArg() > 0 {
+		flags.Parse(flags.Args()[1:])
+	}
+
+	if *help {
+		printImpactHelp()
+		return 0
+	}
+	kind, name, ok := parseImpactTarget(target)
+	if !ok || flags.NArg() > 0 {
+		printImpactHelp()
+		return 2
+	}
+
+	rm, err := NewResourceMapper()
+	if err != nil {
+		fmt.Printf("%sError initializing resource mapper: %v%s\n", common.ColorRed, err, common.ColorReset)
+		return 2
+	}
+
+	if err := rm.processor.ShowRolloutImpact(*namespace, kind, name, *commands); err != nil {
+		fmt.Printf("%sError checking impact of %s %s: %v%s\n", common.ColorRed, kind, name, err, common.ColorReset)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+)
+
+func printLintHelp() {
+	fmt.Println("Run lint rules over the discovered namespace data")
+	fmt.Println("\nUsage:")
+	fmt.Println("  k8s-microlens lint [flags]")
+	fmt.Println("\nFlags:")
+	fmt.Println("  -n, --namespace string     Lint only the specified namespace")
+	fmt.Println("  --exclude-ns string        Exclude specified namespaces (can be specified multiple times)")
+	fmt.Println("  --config string            Lint config file enabling, disabling and tuning rules")
+	fmt.Println("  --fail-on string           Exit non-zero when a finding has at least this severity")
+	fmt.Println("                             (info, warning, error or none; default error)")
+	fmt.Println("  --list-rules               List the available rules and exit")
+	fmt.Println("\nExamples:")
+	fmt.Println("  # Lint a namespace and fail on warnings")
+	fmt.Println("  k8s-microlens lint -n default --fail-on warning")
+}
+
+// runLint implements the lint subcommand and returns the process exit code
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	var (
+		namespace  = flags.String("n", "", "Lint only the specified namespace")
+		excludeNs  stringSliceFlag
+		configPath = flags.String("config", "", "Lint config file enabling, disabling and tuning rules")
+		failOn     = flags.String("fail-on", "", "Exit non-zero when a finding has at least this severity")
+		listRules  = flags.Bool("list-rules", false, "List the available rules and exit")
+		help       = flags.Bool("h", false, "Show help message")
+	)
+	flags.StringVar(namespace, "namespace", "", "Lint only the specified namespace")
+	flags.Var(&excludeNs, "exclude-ns", "Exclude specified namespaces (can be specified multiple times)")
+	flags.BoolVar(help, "help", false, "Show help message")
+	flags.Parse(args)
+
+	if *help {
+		printLintHelp()
+		return 0
+	}
+
+	if *listRules {
+		for _, rule := range common.LintRules() {
+			fmt.Printf("%-32s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		return 0
+	}
+
+	var config *common.LintConfig
+	if *configPath != "" {
+		var err error
+		config, err = common.LoadLintConfig(*configPath)
+		if err != nil {
+			fmt.Printf("%sError loading lint config: %v%s\n", common.ColorRed, err, common.ColorReset)
+			return 2
+		}
+	}
+
+	threshold := "error"
+	if config != nil && config.FailOn != "" {
+		threshold = config.FailOn
+	}
+	if *failOn != "" {
+		threshold = *failOn
+	}
+	failOnNone := threshold == "none"
+	failSeverity, err := common.ParseSeverity(threshold)
+	if err != nil && !failOnNone {
+		fmt.Printf("%sInvalid --fail-on: %v%s\n", common.ColorRed, err, common.ColorReset)
+		return 2
+	}
+
+	rm, err := NewResourceMapper()
+	if err != nil {
+		fmt.Printf("%sError initializing resource mapper: %v%s\n", common.ColorRed, err, common.ColorReset)
+		return 2
+	}
+
+	namespaces, err := rm.getNamespaces(*namespace, excludeNs)
+	if err != nil {
+		fmt.Printf("%sError getting namespaces: %v%s\n", common.ColorRed, err, common.ColorReset)
+		return 2
+	}
+
+	linter := common.NewLinter(rm.clientset, rm.ctx, config)
+	linter.SetDynamicClient(rm.dynamic)
+	failed := false
+	for _, ns := range namespaces {
+		findings, err := linter.Lint(ns)
+		if err != nil {
+			fmt.Printf("%sError linting namespace %s: %v%s\n", common.ColorRed, ns, err, common.ColorReset)
+			failed = true
+			continue
+		}
+		linter.ShowFindings(ns, findings)
+		for _, finding := range findings {
+			if !failOnNone && finding.Severity >= failSeverity {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/mbergo/k8s-microlens/internal/common"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -17,6 +19,7 @@ import (
 // ResourceMapper holds the Kubernetes client and context
 type ResourceMapper struct {
 	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
 	ctx       context.Context
 	formatter *common.Formatter
 	processor *common.ResourceProcessor
@@ -34,6 +37,22 @@ func (s *stringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// writeCostReports writes the cost reports as JSON to path, or to stdout when path is "-"
+func writeCostReports(path string, reports []*common.CostReport) error {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cost reports: %v", err)
+	}
+	if path == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing cost reports: %v", err)
+	}
+	return nil
+}
+
 // NewResourceMapper creates a new ResourceMapper instance
 func NewResourceMapper() (*ResourceMapper, error) {
 	kubeconfig := os.Getenv("KUBECONFIG")
@@ -55,12 +74,19 @@ func NewResourceMapper() (*ResourceMapper, error) {
 		return nil, fmt.Errorf("error creating kubernetes client: %v", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %v", err)
+	}
+
 	ctx := context.Background()
 	formatter := common.NewFormatter()
 	processor := common.NewResourceProcessor(clientset, ctx)
+	processor.SetDynamicClient(dynamicClient)
 
 	return &ResourceMapper{
 		clientset: clientset,
+		dynamic:   dynamicClient,
 		ctx:       ctx,
 		formatter: formatter,
 		processor: processor,
@@ -103,9 +129,21 @@ func printHelp() {
 	fmt.Println("Kubernetes MicroLens - A lightweight Kubernetes resource visualization tool")
 	fmt.Println("\nUsage:")
 	fmt.Println("  k8s-microlens [flags]")
+	fmt.Println("  k8s-microlens lint [flags]")
+	fmt.Println("  k8s-microlens secret-access <name> [flags]")
+	fmt.Println("  k8s-microlens impact configmap/<name>|secret/<name> [flags]")
 	fmt.Println("\nFlags:")
 	fmt.Println("  -n, --namespace string     Process only the specified namespace")
 	fmt.Println("  --exclude-ns string        Exclude specified namespaces (can be specified multiple times)")
+	fmt.Println("  --pricing string           Estimate monthly cost using the given pricing config file")
+	fmt.Println("  --cost-json string         Write cost estimates as JSON to the given file (- prints only the JSON to stdout)")
+	fmt.Println("  --cert-warn-days int       Flag Ingress certificates expiring within this many days, 0 for expired only (default 30)")
+	fmt.Println("  --ingress-controller-ns string")
+	fmt.Println("                             Namespace of the Ingress controller for NetworkPolicy checks (default ingress-nginx)")
+	fmt.Println("  --crd-config string        Map the custom resources declared in the given config file")
+	fmt.Println("  --orphans                  Report ConfigMaps and Secrets that nothing references")
+	fmt.Println("  --orphans-manifest string  Write orphans older than --orphans-min-age to a manifest for kubectl delete -f")
+	fmt.Println("  --orphans-min-age duration Minimum age of orphans written to the manifest (default 168h)")
 	fmt.Println("  -h, --help                Show help message")
 	fmt.Println("  -v, --version             Show version information")
 	fmt.Println("\nExamples:")
@@ -115,6 +153,10 @@ func printHelp() {
 	fmt.Println("  k8s-microlens -n default")
 	fmt.Println("\n  # Exclude specific namespaces")
 	fmt.Println("  k8s-microlens --exclude-ns kube-system --exclude-ns kube-public")
+	fmt.Println("\n  # Estimate monthly cost per namespace and workload")
+	fmt.Println("  k8s-microlens --pricing pricing.yaml --cost-json cost.json")
+	fmt.Println("\n  # List orphaned ConfigMaps and Secrets and write deletion candidates")
+	fmt.Println("  k8s-microlens --orphans --orphans-manifest orphans.yaml")
 }
 
 func printVersion() {
@@ -125,9 +167,28 @@ func printVersion() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "secret-access":
+			os.Exit(runSecretAccess(os.Args[2:]))
+		case "impact":
+			os.Exit(runImpact(os.Args[2:]))
+		}
+	}
+
 	var (
 		namespace = flag.String("n", "", "Process only the specified namespace")
 		excludeNs stringSliceFlag
+		pricing   = flag.String("pricing", "", "Estimate monthly cost using the given pricing config file")
+		costJSON  = flag.String("cost-json", "", "Write cost estimates as JSON to the given file (- prints only the JSON to stdout)")
+		certWarn  = flag.Int("cert-warn-days", 30, "Flag Ingress certificates expiring within this many days, 0 for expired only")
+		ingressNs = flag.String("ingress-controller-ns", "ingress-nginx", "Namespace of the Ingress controller for NetworkPolicy checks")
+		crdConfig = flag.String("crd-config", "", "Map the custom resources declared in the given config file")
+		orphans   = flag.Bool("orphans", false, "Report ConfigMaps and Secrets that nothing references")
+		manifest  = flag.String("orphans-manifest", "", "Write orphans older than --orphans-min-age to a manifest")
+		minAge    = flag.Duration("orphans-min-age", 7*24*time.Hour, "Minimum age of orphans written to the manifest")
 		help      = flag.Bool("h", false, "Show help message")
 		version   = flag.Bool("v", false, "Show version information")
 	)
@@ -155,9 +216,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	rm.formatter.PrintHeader("Kubernetes MicroLens")
-	fmt.Printf("Generated at: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	rm.formatter.PrintLine()
+	if *certWarn < 0 {
+		fmt.Printf("%s--cert-warn-days must not be negative%s\n", common.ColorRed, common.ColorReset)
+		os.Exit(1)
+	}
+	certWindow := time.Duration(*certWarn) * 24 * time.Hour
+	options := common.ProcessorOptions{
+		CertExpiryWindow:           &certWindow,
+		IngressControllerNamespace: *ingressNs,
+	}
+	if *pricing != "" {
+		options.Pricing, err = common.LoadPricingConfig(*pricing)
+		if err != nil {
+			fmt.Printf("%sError loading pricing config: %v%s\n", common.ColorRed, err, common.ColorReset)
+			os.Exit(1)
+		}
+	} else if *costJSON != "" {
+		fmt.Printf("%s--cost-json requires --pricing%s\n", common.ColorRed, common.ColorReset)
+		os.Exit(1)
+	}
+	if *crdConfig != "" {
+		options.CustomResources, err = common.LoadCRDConfig(*crdConfig)
+		if err != nil {
+			fmt.Printf("%sError loading crd config: %v%s\n", common.ColorRed, err, common.ColorReset)
+			os.Exit(1)
+		}
+	}
+	rm.processor.SetOptions(options)
+
+	// With --cost-json - stdout carries only the JSON, so the tree is skipped
+	jsonOnly := *costJSON == "-"
+	if !jsonOnly {
+		rm.formatter.PrintHeader("Kubernetes MicroLens")
+		fmt.Printf("Generated at: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+		rm.formatter.PrintLine()
+	}
 
 	namespaces, err := rm.getNamespaces(*namespace, excludeNs)
 	if err != nil {
@@ -165,13 +258,67 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *orphans || *manifest != "" {
+		var candidates []common.OrphanedObject
+		complete := true
+		for _, ns := range namespaces {
+			found, err := rm.processor.ShowOrphanedConfigObjects(ns)
+			if err != nil {
+				fmt.Printf("%sError finding orphans in namespace %s: %v%s\n", common.ColorRed, ns, err, common.ColorReset)
+				complete = false
+				continue
+			}
+			for _, orphan := range found {
+				if orphan.Age() >= *minAge {
+					candidates = append(candidates, orphan)
+				}
+			}
+		}
+		if *manifest != "" {
+			// A namespace that could not be checked may hide references to the candidates
+			if !complete {
+				fmt.Printf("%sNot writing %s: orphans could not be checked in every namespace%s\n",
+					common.ColorRed, *manifest, common.ColorReset)
+				os.Exit(1)
+			}
+			if err := common.WriteOrphanManifest(*manifest, candidates); err != nil {
+				fmt.Printf("%s%v%s\n", common.ColorRed, err, common.ColorReset)
+				os.Exit(1)
+			}
+			fmt.Printf("\nWrote %d safe-to-delete candidate(s) to %s\n", len(candidates), *manifest)
+		}
+		rm.formatter.PrintSuccess("Orphan report complete!")
+		return
+	}
+
 	// Process each namespace
-	for _, ns := range namespaces {
-		if err := rm.processor.ProcessNamespace(ns); err != nil {
-			fmt.Printf("%sError processing namespace %s: %v%s\n", common.ColorRed, ns, err, common.ColorReset)
-			continue
+	if !jsonOnly {
+		for _, ns := range namespaces {
+			if err := rm.processor.ProcessNamespace(ns); err != nil {
+				fmt.Printf("%sError processing namespace %s: %v%s\n", common.ColorRed, ns, err, common.ColorReset)
+				continue
+			}
+		}
+	}
+
+	if *costJSON != "" {
+		metrics := common.NewResourceMetrics(rm.clientset, rm.formatter)
+		var reports []*common.CostReport
+		for _, ns := range namespaces {
+			report, err := metrics.EstimateNamespaceCost(ns, options.Pricing)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%sError estimating cost for namespace %s: %v%s\n", common.ColorRed, ns, err, common.ColorReset)
+				continue
+			}
+			reports = append(reports, report)
+		}
+		if err := writeCostReports(*costJSON, reports); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%v%s\n", common.ColorRed, err, common.ColorReset)
+			os.Exit(1)
 		}
 	}
 
-	rm.formatter.PrintSuccess("Resource mapping complete!")
+	if !jsonOnly {
+		rm.formatter.PrintSuccess("Resource mapping complete!")
+	}
 }
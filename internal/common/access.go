@@ -0,0 +1,199 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// secretReader is an RBAC subject able to read a Secret, with the grants that allow it
+type secretReader struct {
+	subject   rbacv1.Subject
+	namespace string
+	grants    []accessGrant
+}
+
+// displayName returns the subject name, qualified with the namespace for ServiceAccounts
+func (r *secretReader) displayName() string {
+	if r.subject.Kind == rbacv1.ServiceAccountKind {
+		return r.namespace + "/" + r.subject.Name
+	}
+	return r.subject.Name
+}
+
+// secretReaders groups the grants allowing reads of a Secret by subject
+func (ri *rbacIndex) secretReaders(name string) []*secretReader {
+	var readers []*secretReader
+	bySubject := make(map[string]*secretReader)
+	for _, grant := range ri.grantsAllowing(secretReadVerbs, "", "secrets", name) {
+		for _, subject := range grant.subjects {
+			// A User named after a ServiceAccount is that ServiceAccount
+			if subject.Kind == rbacv1.UserKind {
+				if namespace, name, ok := serviceAccountUser(subject.Name); ok {
+					subject = rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}
+				}
+			}
+			reader := &secretReader{subject: subject, namespace: subject.Namespace}
+			if subject.Kind == rbacv1.ServiceAccountKind && reader.namespace == "" {
+				reader.namespace = grant.namespace
+			}
+			key := subject.Kind + "/" + reader.displayName()
+			if existing, ok := bySubject[key]; ok {
+				reader = existing
+			} else {
+				bySubject[key] = reader
+				readers = append(readers, reader)
+			}
+			reader.grants = append(reader.grants, grant)
+		}
+	}
+	return readers
+}
+
+// ShowSecretAccess lists the workloads consuming a Secret directly and the RBAC
+// subjects that could read it, with the workloads running as those subjects
+func (rp *ResourceProcessor) ShowSecretAccess(namespace, name string) error {
+	rp.formatter.PrintHeader(fmt.Sprintf("Access to Secret %s/%s", namespace, name))
+	rp.formatter.PrintLine()
+
+	secret, err := rp.clientset.CoreV1().Secrets(namespace).Get(rp.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error getting secret: %v", err)
+		}
+		rp.formatter.PrintWarning("Secret not found; showing references and RBAC grants only")
+	} else {
+		rp.formatter.PrintInfo("", "Type: %s", secret.Type)
+		rp.formatter.PrintInfo("", "Data Keys: %d", len(secret.Data))
+	}
+
+	podsByNamespace := make(map[string][]corev1.Pod)
+	listPods := func(ns string) ([]corev1.Pod, error) {
+		if pods, ok := podsByNamespace[ns]; ok {
+			return pods, nil
+		}
+		pods, err := rp.clientset.CoreV1().Pods(ns).List(rp.ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting pods in namespace %s: %v", ns, err)
+		}
+		podsByNamespace[ns] = pods.Items
+		return pods.Items, nil
+	}
+
+	fmt.Println("\n[Direct Consumers]")
+	pods, err := listPods(namespace)
+	if err != nil {
+		return err
+	}
+	sources := rp.loadSecretSources(namespace)
+	rp.warnPartialSources(sources)
+	var consumers []corev1.Pod
+	usages := make(map[string][]string)
+	for _, pod := range pods {
+		if usedAs := rp.getSecretUsageInPod(&pod, name, sources); len(usedAs) > 0 {
+			consumers = append(consumers, pod)
+			usages[pod.Name] = usedAs
+		}
+	}
+	if len(consumers) == 0 {
+		rp.formatter.PrintInfo("", "No pods reference this Secret")
+	}
+	groups := rp.newOwnerResolver(namespace).groupPodsByOwner(consumers)
+	for i, group := range groups {
+		isLast := i == len(groups)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+		rp.formatter.PrintResource(prefix, group.owner.Kind, group.owner.Name)
+		rp.formatter.Indent()
+		var names []string
+		for _, pod := range group.pods {
+			names = append(names, pod.Name)
+		}
+		rp.formatter.PrintInfo("", "Pods: %s", strings.Join(names, ", "))
+		for _, usage := range usages[group.pods[0].Name] {
+			rp.formatter.PrintInfo("", "%s", usage)
+		}
+		rp.formatter.Outdent()
+	}
+
+	fmt.Println("\n[Potential Readers (RBAC)]")
+	index := rp.loadRBACIndex(namespace)
+	if index.partial {
+		rp.formatter.PrintWarning("%s are not readable; readers may be incomplete", strings.Join(index.unreadable, ", "))
+	}
+
+	readers := index.secretReaders(name)
+	if len(readers) == 0 {
+		rp.formatter.PrintStatus("No RBAC subject can read this Secret", true)
+		return nil
+	}
+	for i, reader := range readers {
+		isLast := i == len(readers)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, reader.subject.Kind, reader.displayName())
+		rp.formatter.Indent()
+
+		for _, grant := range reader.grants {
+			rp.formatter.PrintInfo("", "%s via %s (%s/%s)", strings.Join(grant.verbs, ", "), grant.role, grant.kind, grant.name)
+		}
+
+		// Resolve the subject to the pods whose tokens carry it
+		var readerPods []corev1.Pod
+		podsNamespace := ""
+		switch {
+		case reader.subject.Kind == rbacv1.ServiceAccountKind:
+			podsNamespace = reader.namespace
+			all, err := listPods(podsNamespace)
+			if err != nil {
+				return err
+			}
+			for _, pod := range all {
+				if podServiceAccount(&pod) == reader.subject.Name {
+					readerPods = append(readerPods, pod)
+				}
+			}
+		case reader.subject.Kind == rbacv1.GroupKind && strings.HasPrefix(reader.subject.Name, "system:serviceaccounts:"):
+			podsNamespace = strings.TrimPrefix(reader.subject.Name, "system:serviceaccounts:")
+			rp.formatter.PrintWarning("Every ServiceAccount in namespace %s", podsNamespace)
+			if readerPods, err = listPods(podsNamespace); err != nil {
+				return err
+			}
+		case reader.subject.Kind == rbacv1.GroupKind &&
+			(reader.subject.Name == "system:serviceaccounts" || reader.subject.Name == "system:authenticated"):
+			rp.formatter.PrintWarning("Every ServiceAccount in the cluster")
+		default:
+			rp.formatter.PrintInfo("", "Not a workload identity")
+		}
+
+		if podsNamespace != "" {
+			if len(readerPods) == 0 {
+				rp.formatter.PrintInfo("", "No pods run as this subject")
+			}
+			for _, group := range rp.newOwnerResolver(podsNamespace).groupPodsByOwner(readerPods) {
+				details := []string{fmt.Sprintf("Namespace: %s", podsNamespace)}
+				if group.owner.Kind != "Pod" {
+					var names []string
+					for _, pod := range group.pods {
+						names = append(names, pod.Name)
+					}
+					details = append(details, fmt.Sprintf("Pods: %s", strings.Join(names, ", ")))
+				}
+				rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name, details...)
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
@@ -0,0 +1,493 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	kedaGroup = "keda.sh"
+	vpaGroup  = "autoscaling.k8s.io"
+)
+
+// vpaVersions are the VerticalPodAutoscaler API versions in order of preference
+var vpaVersions = []string{"v1", "v1beta2"}
+
+// The types below are the subset of the KEDA and VerticalPodAutoscaler schemas
+// microlens renders, decoded from unstructured objects
+
+type kedaTrigger struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	MetricType string            `json:"metricType"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+type kedaCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type scaledObject struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		ScaleTargetRef struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"scaleTargetRef"`
+		MinReplicaCount *int32        `json:"minReplicaCount"`
+		MaxReplicaCount *int32        `json:"maxReplicaCount"`
+		Triggers        []kedaTrigger `json:"triggers"`
+	} `json:"spec"`
+	Status struct {
+		HPAName    string          `json:"hpaName"`
+		Conditions []kedaCondition `json:"conditions"`
+	} `json:"status"`
+}
+
+type scaledJob struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		JobTargetRef struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"jobTargetRef"`
+		MinReplicaCount *int32 `json:"minReplicaCount"`
+		MaxReplicaCount *int32 `json:"maxReplicaCount"`
+		ScalingStrategy struct {
+			Strategy string `json:"strategy"`
+		} `json:"scalingStrategy"`
+		Triggers []kedaTrigger `json:"triggers"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []kedaCondition `json:"conditions"`
+	} `json:"status"`
+}
+
+type verticalPodAutoscaler struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		TargetRef struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"targetRef"`
+		UpdatePolicy *struct {
+			UpdateMode string `json:"updateMode"`
+		} `json:"updatePolicy"`
+		ResourcePolicy *struct {
+			ContainerPolicies []struct {
+				ContainerName       string   `json:"containerName"`
+				Mode                string   `json:"mode"`
+				ControlledResources []string `json:"controlledResources"`
+			} `json:"containerPolicies"`
+		} `json:"resourcePolicy"`
+	} `json:"spec"`
+	Status struct {
+		Recommendation *struct {
+			ContainerRecommendations []struct {
+				ContainerName string              `json:"containerName"`
+				Target        corev1.ResourceList `json:"target"`
+				LowerBound    corev1.ResourceList `json:"lowerBound"`
+				UpperBound    corev1.ResourceList `json:"upperBound"`
+			} `json:"containerRecommendations"`
+		} `json:"recommendation"`
+	} `json:"status"`
+}
+
+// autoscalers holds the KEDA and VerticalPodAutoscaler objects of a namespace;
+// the lists stay empty when the CRDs are not installed
+type autoscalers struct {
+	scaledObjects []*scaledObject
+	scaledJobs    []*scaledJob
+	vpas          []*verticalPodAutoscaler
+	// warnings describes the lists and objects that could not be read
+	warnings []string
+}
+
+func (a *autoscalers) empty() bool {
+	return len(a.scaledObjects) == 0 && len(a.scaledJobs) == 0 && len(a.vpas) == 0
+}
+
+// loadAutoscalers lists the ScaledObjects, ScaledJobs and VerticalPodAutoscalers
+// of a namespace whose CRDs the API server serves. A list that fails or an
+// object that does not decode is recorded as a warning and skipped so the
+// other autoscalers still render.
+func (rp *ResourceProcessor) loadAutoscalers(namespace string) *autoscalers {
+	result := &autoscalers{}
+	warn := func(format string, a ...interface{}) {
+		result.warnings = append(result.warnings, fmt.Sprintf(format, a...))
+	}
+
+	if gvr, ok := rp.findResource(kedaGroup, "scaledobjects", "v1alpha1"); ok {
+		items, err := rp.listDynamic(gvr, namespace)
+		if err != nil {
+			warn("Could not list ScaledObjects: %v", err)
+		}
+		for i := range items {
+			so := &scaledObject{}
+			if err := decodeUnstructured(&items[i], so); err != nil {
+				warn("Could not decode ScaledObject %s: %v", items[i].GetName(), err)
+				continue
+			}
+			result.scaledObjects = append(result.scaledObjects, so)
+		}
+	}
+
+	if gvr, ok := rp.findResource(kedaGroup, "scaledjobs", "v1alpha1"); ok {
+		items, err := rp.listDynamic(gvr, namespace)
+		if err != nil {
+			warn("Could not list ScaledJobs: %v", err)
+		}
+		for i := range items {
+			sj := &scaledJob{}
+			if err := decodeUnstructured(&items[i], sj); err != nil {
+				warn("Could not decode ScaledJob %s: %v", items[i].GetName(), err)
+				continue
+			}
+			result.scaledJobs = append(result.scaledJobs, sj)
+		}
+	}
+
+	if gvr, ok := rp.findResource(vpaGroup, "verticalpodautoscalers", vpaVersions...); ok {
+		items, err := rp.listDynamic(gvr, namespace)
+		if err != nil {
+			warn("Could not list VerticalPodAutoscalers: %v", err)
+		}
+		for i := range items {
+			vpa := &verticalPodAutoscaler{}
+			if err := decodeUnstructured(&items[i], vpa); err != nil {
+				warn("Could not decode VerticalPodAutoscaler %s: %v", items[i].GetName(), err)
+				continue
+			}
+			result.vpas = append(result.vpas, vpa)
+		}
+	}
+
+	return result
+}
+
+// target returns the workload a ScaledObject scales; the kind defaults to Deployment
+func (so *scaledObject) target() workloadRef {
+	kind := so.Spec.ScaleTargetRef.Kind
+	if kind == "" {
+		kind = "Deployment"
+	}
+	return workloadRef{Kind: kind, Name: so.Spec.ScaleTargetRef.Name}
+}
+
+// hpaName returns the HPA KEDA generates for a ScaledObject
+func (so *scaledObject) hpaName() string {
+	if so.Status.HPAName != "" {
+		return so.Status.HPAName
+	}
+	return "keda-hpa-" + so.Metadata.Name
+}
+
+// describeReplicaCount renders a KEDA replica bound, which defaults when unset
+func describeReplicaCount(count *int32, defaultCount int32) string {
+	if count == nil {
+		return fmt.Sprintf("%d (default)", defaultCount)
+	}
+	return fmt.Sprintf("%d", *count)
+}
+
+// describeTrigger renders a KEDA trigger, e.g. "cpu (Utilization): value=60"
+func describeTrigger(trigger kedaTrigger) string {
+	line := trigger.Type
+	if trigger.Name != "" {
+		line += fmt.Sprintf(" '%s'", trigger.Name)
+	}
+	if trigger.MetricType != "" {
+		line += fmt.Sprintf(" (%s)", trigger.MetricType)
+	}
+	keys := make([]string, 0, len(trigger.Metadata))
+	for key := range trigger.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = fmt.Sprintf("%s=%s", key, trigger.Metadata[key])
+	}
+	if len(keys) > 0 {
+		line += ": " + strings.Join(keys, ", ")
+	}
+	return line
+}
+
+// kedaConditionHealthy reports whether a KEDA condition status is the healthy
+// one; Fallback and Paused are healthy when false, Active either way
+func kedaConditionHealthy(condition kedaCondition) bool {
+	switch condition.Type {
+	case "Active":
+		return true
+	case "Fallback", "Paused":
+		return condition.Status != string(corev1.ConditionTrue)
+	}
+	return condition.Status == string(corev1.ConditionTrue)
+}
+
+// showKEDAConditions prints the status conditions of a ScaledObject or ScaledJob
+func (rp *ResourceProcessor) showKEDAConditions(conditions []kedaCondition) {
+	for _, condition := range conditions {
+		line := fmt.Sprintf("%s: %s", condition.Type, condition.Status)
+		if condition.Reason != "" {
+			line += fmt.Sprintf(" (%s)", condition.Reason)
+		}
+		if condition.Message != "" {
+			line += ": " + condition.Message
+		}
+		rp.formatter.PrintStatus(line, kedaConditionHealthy(condition))
+	}
+}
+
+// updateMode returns the update mode of a VPA, which defaults to Auto
+func (vpa *verticalPodAutoscaler) updateMode() (string, bool) {
+	if vpa.Spec.UpdatePolicy == nil || vpa.Spec.UpdatePolicy.UpdateMode == "" {
+		return "Auto", true
+	}
+	return vpa.Spec.UpdatePolicy.UpdateMode, false
+}
+
+// controlledResources returns the resources a VPA changes the requests of.
+// Containers without a policy of their own, and policies without controlled
+// resources, cover cpu and memory; nothing is changed in Off mode.
+func (vpa *verticalPodAutoscaler) controlledResources() []string {
+	if mode, _ := vpa.updateMode(); mode == "Off" {
+		return nil
+	}
+
+	resources := make(map[string]bool)
+	wildcard := false
+	if vpa.Spec.ResourcePolicy != nil {
+		for _, policy := range vpa.Spec.ResourcePolicy.ContainerPolicies {
+			if policy.ContainerName == "*" {
+				wildcard = true
+			}
+			if policy.Mode == "Off" {
+				continue
+			}
+			controlled := policy.ControlledResources
+			if len(controlled) == 0 {
+				controlled = []string{string(corev1.ResourceCPU), string(corev1.ResourceMemory)}
+			}
+			for _, resource := range controlled {
+				resources[resource] = true
+			}
+		}
+	}
+	if !wildcard {
+		resources[string(corev1.ResourceCPU)] = true
+		resources[string(corev1.ResourceMemory)] = true
+	}
+	return sortedKeys(resources)
+}
+
+// describeResourceList renders a resource list, e.g. "cpu 250m, memory 256Mi"
+func describeResourceList(list corev1.ResourceList) string {
+	names := make([]string, 0, len(list))
+	for name := range list {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		quantity := list[corev1.ResourceName(name)]
+		names[i] = fmt.Sprintf("%s %s", name, quantity.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// resourceScaler is an HPA or ScaledObject scaling a workload on cpu or memory
+type resourceScaler struct {
+	name      string
+	resources map[string]bool
+}
+
+// hpaScaledResources returns the resources an HPA scales on through Resource
+// and ContainerResource metrics, CPU for an HPA without metrics
+func hpaScaledResources(hpa *autoscalingv2.HorizontalPodAutoscaler) map[string]bool {
+	resources := make(map[string]bool)
+	metrics, _ := hpaMetrics(hpa)
+	for _, metric := range metrics {
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+			resources[string(metric.Resource.Name)] = true
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+			resources[string(metric.ContainerResource.Name)] = true
+		}
+	}
+	return resources
+}
+
+// triggerScaledResources returns the resources a ScaledObject scales on
+// through cpu and memory triggers
+func triggerScaledResources(so *scaledObject) map[string]bool {
+	resources := make(map[string]bool)
+	for _, trigger := range so.Spec.Triggers {
+		switch trigger.Type {
+		case string(corev1.ResourceCPU), string(corev1.ResourceMemory):
+			resources[trigger.Type] = true
+		}
+	}
+	return resources
+}
+
+// resourceScalers indexes by workload the HPAs and ScaledObjects scaling on
+// cpu or memory. HPAs generated for a known ScaledObject are left out so the
+// pair is reported once.
+func resourceScalers(hpas []autoscalingv2.HorizontalPodAutoscaler, scaledObjects []*scaledObject) map[workloadRef][]resourceScaler {
+	generated := make(map[string]bool)
+	scalers := make(map[workloadRef][]resourceScaler)
+	for _, so := range scaledObjects {
+		generated[so.hpaName()] = true
+		if resources := triggerScaledResources(so); len(resources) > 0 {
+			scalers[so.target()] = append(scalers[so.target()],
+				resourceScaler{name: "ScaledObject " + so.Metadata.Name, resources: resources})
+		}
+	}
+	for i := range hpas {
+		hpa := &hpas[i]
+		if generated[hpa.Name] {
+			continue
+		}
+		if resources := hpaScaledResources(hpa); len(resources) > 0 {
+			ref := workloadRef{Kind: hpa.Spec.ScaleTargetRef.Kind, Name: hpa.Spec.ScaleTargetRef.Name}
+			scalers[ref] = append(scalers[ref], resourceScaler{name: "HPA " + hpa.Name, resources: resources})
+		}
+	}
+	return scalers
+}
+
+// checkVPAConflicts flags a VPA changing the requests of a resource that an
+// HPA or ScaledObject of the same workload scales on: each resize moves the
+// utilization the horizontal scaler acts on, and the two fight
+func checkVPAConflicts(lc *lintContext, rule *activeRule) {
+	scalers := resourceScalers(lc.hpas, lc.scaledObjects)
+	for _, vpa := range lc.vpas {
+		target := workloadRef{Kind: vpa.Spec.TargetRef.Kind, Name: vpa.Spec.TargetRef.Name}
+		controlled := vpa.controlledResources()
+		for _, scaler := range scalers[target] {
+			var shared []string
+			for _, name := range controlled {
+				if scaler.resources[name] {
+					shared = append(shared, name)
+				}
+			}
+			if len(shared) > 0 {
+				rule.report("VerticalPodAutoscaler/"+vpa.Metadata.Name, "updates %s requests of %s, which %s scales on",
+					strings.Join(shared, " and "), target, scaler.name)
+			}
+		}
+	}
+}
+
+// showScaledObject prints a KEDA ScaledObject with its triggers and the HPA it
+// generates
+func (rp *ResourceProcessor) showScaledObject(so *scaledObject, targets *lintContext, hpaNames map[string]bool) {
+	ref := so.target()
+	if target := targets.findScaleTarget(ref.Kind, ref.Name); target != nil {
+		rp.formatter.PrintInfo("", "Target: %s (%d/%d replicas ready)", ref, target.ready, target.replicas)
+	} else {
+		rp.formatter.PrintInfo("", "Target: %s", ref)
+	}
+	rp.formatter.PrintInfo("", "Min Replicas: %s", describeReplicaCount(so.Spec.MinReplicaCount, 0))
+	rp.formatter.PrintInfo("", "Max Replicas: %s", describeReplicaCount(so.Spec.MaxReplicaCount, 100))
+	for _, trigger := range so.Spec.Triggers {
+		rp.formatter.PrintInfo("", "Trigger: %s", describeTrigger(trigger))
+	}
+	if hpaNames[so.hpaName()] {
+		rp.formatter.PrintInfo("", "Generated HPA: %s", so.hpaName())
+	} else {
+		rp.formatter.PrintStatus(fmt.Sprintf("Generated HPA: %s (not found)", so.hpaName()), false)
+	}
+	rp.showKEDAConditions(so.Status.Conditions)
+}
+
+// showScaledJob prints a KEDA ScaledJob with its triggers and scaling strategy
+func (rp *ResourceProcessor) showScaledJob(sj *scaledJob) {
+	var containers []string
+	for _, container := range sj.Spec.JobTargetRef.Template.Spec.Containers {
+		containers = append(containers, container.Name)
+	}
+	if len(containers) > 0 {
+		rp.formatter.PrintInfo("", "Job Containers: %s", strings.Join(containers, ", "))
+	}
+	rp.formatter.PrintInfo("", "Min Replicas: %s", describeReplicaCount(sj.Spec.MinReplicaCount, 0))
+	rp.formatter.PrintInfo("", "Max Replicas: %s", describeReplicaCount(sj.Spec.MaxReplicaCount, 100))
+	if sj.Spec.ScalingStrategy.Strategy != "" {
+		rp.formatter.PrintInfo("", "Scaling Strategy: %s", sj.Spec.ScalingStrategy.Strategy)
+	} else {
+		rp.formatter.PrintInfo("", "Scaling Strategy: default")
+	}
+	for _, trigger := range sj.Spec.Triggers {
+		rp.formatter.PrintInfo("", "Trigger: %s", describeTrigger(trigger))
+	}
+	rp.showKEDAConditions(sj.Status.Conditions)
+}
+
+// showVPA prints a VerticalPodAutoscaler with its update mode, the
+// recommendation for each container next to its current requests and its
+// lint findings
+func (rp *ResourceProcessor) showVPA(vpa *verticalPodAutoscaler, targets *lintContext, lintFindings []Finding) {
+	ref := workloadRef{Kind: vpa.Spec.TargetRef.Kind, Name: vpa.Spec.TargetRef.Name}
+	target := targets.findScaleTarget(ref.Kind, ref.Name)
+	rp.formatter.PrintInfo("", "Target: %s", ref)
+	if mode, defaulted := vpa.updateMode(); defaulted {
+		rp.formatter.PrintInfo("", "Update Mode: %s (default)", mode)
+	} else {
+		rp.formatter.PrintInfo("", "Update Mode: %s", mode)
+	}
+	if controlled := vpa.controlledResources(); len(controlled) > 0 {
+		rp.formatter.PrintInfo("", "Controlled Resources: %s", strings.Join(controlled, ", "))
+	}
+
+	if vpa.Status.Recommendation == nil || len(vpa.Status.Recommendation.ContainerRecommendations) == 0 {
+		rp.formatter.PrintStatus("Recommendations: not available yet", false)
+	} else {
+		rp.formatter.PrintInfo("", "Recommendations:")
+		for _, recommendation := range vpa.Status.Recommendation.ContainerRecommendations {
+			line := fmt.Sprintf("  Container %s: %s", recommendation.ContainerName, describeResourceList(recommendation.Target))
+			if len(recommendation.LowerBound) > 0 || len(recommendation.UpperBound) > 0 {
+				line += fmt.Sprintf(" (lower: %s; upper: %s)",
+					describeResourceList(recommendation.LowerBound), describeResourceList(recommendation.UpperBound))
+			}
+			rp.formatter.PrintInfo("", "%s", line)
+			if target == nil {
+				continue
+			}
+			for _, container := range target.spec.Containers {
+				if container.Name == recommendation.ContainerName {
+					requests := describeResourceList(container.Resources.Requests)
+					if requests == "" {
+						requests = "none"
+					}
+					rp.formatter.PrintInfo("", "    Current Requests: %s", requests)
+				}
+			}
+		}
+	}
+
+	if findings := findingsFor(lintFindings, "VerticalPodAutoscaler/"+vpa.Metadata.Name); len(findings) > 0 {
+		rp.formatter.PrintInfo("", "Findings:")
+		printFindings(rp.formatter, findings)
+	}
+}
+
+// scaledObjectOf returns the ScaledObject owning a KEDA-generated HPA, or ""
+func scaledObjectOf(hpa *autoscalingv2.HorizontalPodAutoscaler) string {
+	if owner := metav1.GetControllerOf(hpa); owner != nil && owner.Kind == "ScaledObject" {
+		return owner.Name
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ShowBatchDetails shows CronJobs with the Jobs they created, followed by Jobs
+// that no CronJob owns
+func (rp *ResourceProcessor) ShowBatchDetails(namespace string) error {
+	fmt.Println("\n[Batch Layer]")
+	cronJobs, err := rp.clientset.BatchV1().CronJobs(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting cronjobs: %v", err)
+	}
+	jobs, err := rp.clientset.BatchV1().Jobs(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting jobs: %v", err)
+	}
+	configMaps, err := rp.clientset.CoreV1().ConfigMaps(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting configmaps: %v", err)
+	}
+	secrets, err := rp.clientset.CoreV1().Secrets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting secrets: %v", err)
+	}
+	sources := rp.loadSecretSources(namespace)
+	rp.warnPartialSources(sources)
+
+	// Group Jobs by the CronJob that created them, newest first
+	jobsByCronJob := make(map[string][]batchv1.Job)
+	var standalone []batchv1.Job
+	for _, job := range jobs.Items {
+		if owner := metav1.GetControllerOf(&job); owner != nil && owner.Kind == "CronJob" {
+			jobsByCronJob[owner.Name] = append(jobsByCronJob[owner.Name], job)
+		} else {
+			standalone = append(standalone, job)
+		}
+	}
+	for _, cronJobJobs := range jobsByCronJob {
+		sortJobsNewestFirst(cronJobJobs)
+	}
+	sortJobsNewestFirst(standalone)
+
+	total := len(cronJobs.Items) + len(standalone)
+	index := 0
+	nextPrefix := func() string {
+		index++
+		if index == total {
+			return "└──"
+		}
+		return "├──"
+	}
+
+	for _, cronJob := range cronJobs.Items {
+		rp.formatter.PrintResource(nextPrefix(), "CronJob", cronJob.Name)
+		rp.formatter.Indent()
+
+		rp.formatter.PrintInfo("", "Schedule: %s", cronJob.Spec.Schedule)
+		if cronJob.Spec.TimeZone != nil {
+			rp.formatter.PrintInfo("", "Time Zone: %s", *cronJob.Spec.TimeZone)
+		}
+		suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+		rp.formatter.PrintStatus(fmt.Sprintf("Suspended: %t", suspended), !suspended)
+		rp.formatter.PrintInfo("", "Concurrency Policy: %s", cronJob.Spec.ConcurrencyPolicy)
+		if cronJob.Status.LastScheduleTime != nil {
+			rp.formatter.PrintInfo("", "Last Schedule: %s", formatTimestamp(cronJob.Status.LastScheduleTime))
+		}
+		if cronJob.Status.LastSuccessfulTime != nil {
+			rp.formatter.PrintInfo("", "Last Success: %s", formatTimestamp(cronJob.Status.LastSuccessfulTime))
+		}
+		if len(cronJob.Status.Active) > 0 {
+			rp.formatter.PrintInfo("", "Active Jobs: %d", len(cronJob.Status.Active))
+		}
+
+		template := &corev1.Pod{Spec: cronJob.Spec.JobTemplate.Spec.Template.Spec}
+		rp.showConfigConsumption(template, configMaps.Items, secrets.Items, sources)
+
+		for _, job := range jobsByCronJob[cronJob.Name] {
+			if err := rp.showJob(namespace, &job); err != nil {
+				return err
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	for _, job := range standalone {
+		rp.formatter.PrintResource(nextPrefix(), "Job", job.Name)
+		rp.formatter.Indent()
+		template := &corev1.Pod{Spec: job.Spec.Template.Spec}
+		rp.showConfigConsumption(template, configMaps.Items, secrets.Items, sources)
+		if err := rp.showJobRun(namespace, &job); err != nil {
+			return err
+		}
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+func sortJobsNewestFirst(jobs []batchv1.Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[j].CreationTimestamp.Before(&jobs[i].CreationTimestamp)
+	})
+}
+
+func formatTimestamp(t *metav1.Time) string {
+	return fmt.Sprintf("%s (%s ago)", t.Format("2006-01-02 15:04:05"), formatAge(time.Since(t.Time)))
+}
+
+// showConfigConsumption prints the ConfigMaps and Secrets a pod template consumes
+func (rp *ResourceProcessor) showConfigConsumption(pod *corev1.Pod, configMaps []corev1.ConfigMap, secrets []corev1.Secret, sources *secretSources) {
+	for _, cm := range configMaps {
+		if usedAs := rp.getConfigMapUsageInPod(pod, cm.Name); len(usedAs) > 0 {
+			rp.formatter.PrintRelation("ConfigMap", cm.Name, usedAs...)
+		}
+	}
+	for _, secret := range secrets {
+		if usedAs := rp.getSecretUsageInPod(pod, secret.Name, sources); len(usedAs) > 0 {
+			rp.formatter.PrintRelation("Secret", secret.Name, usedAs...)
+		}
+	}
+}
+
+// showJob prints one Job run nested under its CronJob
+func (rp *ResourceProcessor) showJob(namespace string, job *batchv1.Job) error {
+	rp.formatter.PrintRelation("Job", job.Name)
+	rp.formatter.Indent()
+	defer rp.formatter.Outdent()
+	return rp.showJobRun(namespace, job)
+}
+
+// jobEndTime returns when a Job finished: its completion time, or for a failed
+// Job, which never gets one, the time it was marked Failed or FailureTarget
+func jobEndTime(job *batchv1.Job) (time.Time, bool) {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time, true
+	}
+	var end time.Time
+	found := false
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobFailed:
+			return condition.LastTransitionTime.Time, true
+		case batchv1.JobFailureTarget:
+			end, found = condition.LastTransitionTime.Time, true
+		}
+	}
+	return end, found
+}
+
+// showJobRun prints the completions, failures, duration and pods of a Job
+func (rp *ResourceProcessor) showJobRun(namespace string, job *batchv1.Job) error {
+	var completions int32 = 1
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	rp.formatter.PrintInfo("", "Completions: %d/%d", job.Status.Succeeded, completions)
+	if job.Status.Failed > 0 {
+		backoffLimit := int32(6)
+		if job.Spec.BackoffLimit != nil {
+			backoffLimit = *job.Spec.BackoffLimit
+		}
+		rp.formatter.PrintStatus(fmt.Sprintf("Failures: %d (backoff limit %d)", job.Status.Failed, backoffLimit), false)
+	}
+	if job.Status.Active > 0 {
+		rp.formatter.PrintInfo("", "Active: %d", job.Status.Active)
+	}
+
+	if job.Status.StartTime != nil {
+		if end, finished := jobEndTime(job); finished {
+			rp.formatter.PrintInfo("", "Duration: %s", end.Sub(job.Status.StartTime.Time).Round(time.Second))
+		} else {
+			rp.formatter.PrintInfo("", "Running for: %s", time.Since(job.Status.StartTime.Time).Round(time.Second))
+		}
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			rp.formatter.PrintStatus("Complete", true)
+		case batchv1.JobFailed:
+			rp.formatter.PrintStatus(fmt.Sprintf("Failed: %s %s", condition.Reason, condition.Message), false)
+		}
+	}
+
+	pods, err := rp.podsForSelector(namespace, job.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("error getting pods for job %s: %v", job.Name, err)
+	}
+	for _, pod := range ownedPods(pods, job) {
+		details := []string{fmt.Sprintf("Status: %s", pod.Status.Phase)}
+		if pod.Spec.NodeName != "" {
+			details = append(details, fmt.Sprintf("Node: %s", pod.Spec.NodeName))
+		}
+		rp.formatter.PrintRelation("Pod", pod.Name, details...)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Container types, as named in usage and container listings
+const (
+	appContainerType       = "container"
+	initContainerType      = "init container"
+	sidecarContainerType   = "sidecar container"
+	ephemeralContainerType = "ephemeral container"
+)
+
+// typedContainer is a container of a pod spec together with the role it plays
+type typedContainer struct {
+	corev1.Container
+	containerType string
+}
+
+// label returns the capitalised container type, e.g. "Init Container"
+func (c typedContainer) label() string {
+	words := strings.Fields(c.containerType)
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// isSidecar reports whether an init container is a restartable sidecar that
+// keeps running alongside the app containers
+func isSidecar(container *corev1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// servingContainers returns the containers of a pod spec that keep running
+// and can serve traffic: sidecars and app containers
+func servingContainers(spec *corev1.PodSpec) []corev1.Container {
+	var containers []corev1.Container
+	for _, container := range spec.InitContainers {
+		if isSidecar(&container) {
+			containers = append(containers, container)
+		}
+	}
+	return append(containers, spec.Containers...)
+}
+
+// findContainerPort returns the container port a Service port targets, by
+// name or number and with the same protocol; both default to TCP. An unset
+// targetPort targets the Service port number.
+func findContainerPort(spec *corev1.PodSpec, port corev1.ServicePort) (corev1.ContainerPort, bool) {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	target := port.TargetPort.IntVal
+	if target == 0 {
+		target = port.Port
+	}
+	for _, container := range servingContainers(spec) {
+		for _, containerPort := range container.Ports {
+			containerProtocol := containerPort.Protocol
+			if containerProtocol == "" {
+				containerProtocol = corev1.ProtocolTCP
+			}
+			if containerProtocol != protocol {
+				continue
+			}
+			if port.TargetPort.Type == intstr.String {
+				if containerPort.Name == port.TargetPort.StrVal {
+					return containerPort, true
+				}
+			} else if containerPort.ContainerPort == target {
+				return containerPort, true
+			}
+		}
+	}
+	return corev1.ContainerPort{}, false
+}
+
+// podContainers returns every container of a pod spec with its type: init
+// containers and sidecars in their start order, then app and ephemeral containers
+func podContainers(spec *corev1.PodSpec) []typedContainer {
+	containers := make([]typedContainer, 0,
+		len(spec.InitContainers)+len(spec.Containers)+len(spec.EphemeralContainers))
+	for _, container := range spec.InitContainers {
+		containerType := initContainerType
+		if isSidecar(&container) {
+			containerType = sidecarContainerType
+		}
+		containers = append(containers, typedContainer{container, containerType})
+	}
+	for _, container := range spec.Containers {
+		containers = append(containers, typedContainer{container, appContainerType})
+	}
+	for _, container := range spec.EphemeralContainers {
+		containers = append(containers, typedContainer{
+			corev1.Container(container.EphemeralContainerCommon), ephemeralContainerType,
+		})
+	}
+	return containers
+}
+
+// effectiveResources computes the CPU (millicores) and memory (bytes) a pod
+// spec needs without overhead, following the scheduler's rules: the larger of
+// the app containers plus sidecars, and of each init container plus the
+// sidecars started before it
+func effectiveResources(spec *corev1.PodSpec, pick func(corev1.ResourceRequirements) corev1.ResourceList) (int64, int64) {
+	var cpu, memory int64
+	for _, container := range spec.Containers {
+		list := pick(container.Resources)
+		cpu += list.Cpu().MilliValue()
+		memory += list.Memory().Value()
+	}
+
+	var sidecarCPU, sidecarMemory, initCPU, initMemory int64
+	for _, container := range spec.InitContainers {
+		list := pick(container.Resources)
+		containerCPU, containerMemory := list.Cpu().MilliValue(), list.Memory().Value()
+		if isSidecar(&container) {
+			// Sidecars keep running, so they add to the app containers too
+			cpu += containerCPU
+			memory += containerMemory
+			sidecarCPU += containerCPU
+			sidecarMemory += containerMemory
+			containerCPU, containerMemory = sidecarCPU, sidecarMemory
+		} else {
+			containerCPU += sidecarCPU
+			containerMemory += sidecarMemory
+		}
+		initCPU = max(initCPU, containerCPU)
+		initMemory = max(initMemory, containerMemory)
+	}
+
+	return max(cpu, initCPU), max(memory, initMemory)
+}
+
+// podSpecRequests returns the effective CPU (millicores) and memory (bytes)
+// requests of a pod spec, including the pod overhead
+func podSpecRequests(spec *corev1.PodSpec) (int64, int64) {
+	cpu, memory := effectiveResources(spec, func(r corev1.ResourceRequirements) corev1.ResourceList {
+		return r.Requests
+	})
+	return cpu + spec.Overhead.Cpu().MilliValue(), memory + spec.Overhead.Memory().Value()
+}
+
+// podSpecLimits returns the effective CPU (millicores) and memory (bytes)
+// limits of a pod spec. The pod overhead is only added to limits that are set.
+func podSpecLimits(spec *corev1.PodSpec) (int64, int64) {
+	cpu, memory := effectiveResources(spec, func(r corev1.ResourceRequirements) corev1.ResourceList {
+		return r.Limits
+	})
+	if cpu > 0 {
+		cpu += spec.Overhead.Cpu().MilliValue()
+	}
+	if memory > 0 {
+		memory += spec.Overhead.Memory().Value()
+	}
+	return cpu, memory
+}
@@ -0,0 +1,227 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// hoursPerMonth is the average number of hours in a month used for cost projections
+const hoursPerMonth = 730
+
+// PricingConfig describes the hourly price of requested CPU and memory
+type PricingConfig struct {
+	Currency         string            `json:"currency,omitempty"`
+	CPUPerHour       float64           `json:"cpuPerHour"`
+	MemoryGiBPerHour float64           `json:"memoryGiBPerHour"`
+	NodePools        []NodePoolPricing `json:"nodePools,omitempty"`
+}
+
+// NodePoolPricing overrides the default prices for nodes matching NodeSelector
+type NodePoolPricing struct {
+	Name             string            `json:"name"`
+	NodeSelector     map[string]string `json:"nodeSelector"`
+	CPUPerHour       float64           `json:"cpuPerHour"`
+	MemoryGiBPerHour float64           `json:"memoryGiBPerHour"`
+}
+
+// WorkloadCost is the monthly cost attributed to a single Deployment or Service
+type WorkloadCost struct {
+	Name        string  `json:"name"`
+	Pods        int     `json:"pods"`
+	CPUMillis   int64   `json:"cpuMillis"`
+	MemoryBytes int64   `json:"memoryBytes"`
+	MonthlyCost float64 `json:"monthlyCost"`
+}
+
+// CostReport is the monthly cost attributed to a namespace and its workloads
+type CostReport struct {
+	Namespace   string         `json:"namespace"`
+	Currency    string         `json:"currency"`
+	Pods        int            `json:"pods"`
+	CPUMillis   int64          `json:"cpuMillis"`
+	MemoryBytes int64          `json:"memoryBytes"`
+	MonthlyCost float64        `json:"monthlyCost"`
+	Deployments []WorkloadCost `json:"deployments"`
+	Services    []WorkloadCost `json:"services"`
+}
+
+// LoadPricingConfig reads a pricing config from a YAML or JSON file
+func LoadPricingConfig(path string) (*PricingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pricing config: %v", err)
+	}
+
+	var config PricingConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing pricing config: %v", err)
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	return &config, nil
+}
+
+// ratesFor returns the CPU and memory hourly rates that apply to a node
+func (pc *PricingConfig) ratesFor(node *corev1.Node) (float64, float64) {
+	if node != nil {
+		for _, pool := range pc.NodePools {
+			if labels.SelectorFromSet(pool.NodeSelector).Matches(labels.Set(node.Labels)) {
+				return pool.CPUPerHour, pool.MemoryGiBPerHour
+			}
+		}
+	}
+	return pc.CPUPerHour, pc.MemoryGiBPerHour
+}
+
+// monthlyCost prices the given requests at the given hourly rates
+func monthlyCost(cpuMillis, memoryBytes int64, cpuRate, memoryRate float64) float64 {
+	cores := float64(cpuMillis) / 1000
+	gib := float64(memoryBytes) / (1024 * 1024 * 1024)
+	return (cores*cpuRate + gib*memoryRate) * hoursPerMonth
+}
+
+// EstimateNamespaceCost attributes the monthly cost of pod requests to a namespace,
+// its Deployments and its Services
+func (rm *ResourceMetrics) EstimateNamespaceCost(namespace string, pricing *PricingConfig) (*CostReport, error) {
+	ctx := context.Background()
+
+	pods, err := rm.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %v", err)
+	}
+
+	nodes := make(map[string]*corev1.Node)
+	if len(pricing.NodePools) > 0 {
+		nodeList, err := rm.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting nodes: %v", err)
+		}
+		for i := range nodeList.Items {
+			nodes[nodeList.Items[i].Name] = &nodeList.Items[i]
+		}
+	}
+
+	// Map ReplicaSets to the Deployments that own them
+	replicaSets, err := rm.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting replicasets: %v", err)
+	}
+	rsOwner := make(map[string]string)
+	for _, rs := range replicaSets.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.Kind == "Deployment" {
+			rsOwner[rs.Name] = owner.Name
+		}
+	}
+
+	services, err := rm.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting services: %v", err)
+	}
+
+	report := &CostReport{Namespace: namespace, Currency: pricing.Currency}
+	deployments := make(map[string]*WorkloadCost)
+	serviceCosts := make(map[string]*WorkloadCost)
+
+	add := func(target *WorkloadCost, cpu, memory int64, cost float64) {
+		target.Pods++
+		target.CPUMillis += cpu
+		target.MemoryBytes += memory
+		target.MonthlyCost += cost
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		cpu, memory := podRequests(&pod)
+		cpuRate, memoryRate := pricing.ratesFor(nodes[pod.Spec.NodeName])
+		cost := monthlyCost(cpu, memory, cpuRate, memoryRate)
+
+		report.Pods++
+		report.CPUMillis += cpu
+		report.MemoryBytes += memory
+		report.MonthlyCost += cost
+
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "ReplicaSet" {
+			if deployName, ok := rsOwner[owner.Name]; ok {
+				if deployments[deployName] == nil {
+					deployments[deployName] = &WorkloadCost{Name: deployName}
+				}
+				add(deployments[deployName], cpu, memory, cost)
+			}
+		}
+
+		for _, service := range services.Items {
+			if len(service.Spec.Selector) == 0 {
+				continue
+			}
+			if labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+				if serviceCosts[service.Name] == nil {
+					serviceCosts[service.Name] = &WorkloadCost{Name: service.Name}
+				}
+				add(serviceCosts[service.Name], cpu, memory, cost)
+			}
+		}
+	}
+
+	report.Deployments = sortedWorkloadCosts(deployments)
+	report.Services = sortedWorkloadCosts(serviceCosts)
+	return report, nil
+}
+
+// sortedWorkloadCosts returns workload costs ordered from most to least expensive
+func sortedWorkloadCosts(costs map[string]*WorkloadCost) []WorkloadCost {
+	result := make([]WorkloadCost, 0, len(costs))
+	for _, cost := range costs {
+		result = append(result, *cost)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].MonthlyCost == result[j].MonthlyCost {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].MonthlyCost > result[j].MonthlyCost
+	})
+	return result
+}
+
+// ShowCostEstimation prints the monthly cost summary table for a namespace
+func (rm *ResourceMetrics) ShowCostEstimation(namespace string, pricing *PricingConfig) error {
+	fmt.Printf("\n[Cost Estimation: %s]\n", namespace)
+
+	report, err := rm.EstimateNamespaceCost(namespace, pricing)
+	if err != nil {
+		return err
+	}
+
+	rm.formatter.PrintInfo("", "Namespace Total: %.2f %s/month (%d pods, CPU %s, Memory %s)",
+		report.MonthlyCost, report.Currency, report.Pods,
+		rm.formatCPU(report.CPUMillis), rm.formatMemory(report.MemoryBytes))
+
+	rm.printCostTable("Deployment", report.Deployments, report.Currency)
+	rm.printCostTable("Service", report.Services, report.Currency)
+
+	return nil
+}
+
+func (rm *ResourceMetrics) printCostTable(kind string, costs []WorkloadCost, currency string) {
+	if len(costs) == 0 {
+		return
+	}
+
+	rm.formatter.PrintInfo("", "%-40s %5s %10s %12s %14s", kind, "Pods", "CPU", "Memory", "Cost/month")
+	for _, cost := range costs {
+		rm.formatter.PrintInfo("", "%-40s %5d %10s %12s %10.2f %s",
+			cost.Name, cost.Pods,
+			rm.formatCPU(cost.CPUMillis), rm.formatMemory(cost.MemoryBytes),
+			cost.MonthlyCost, currency)
+	}
+}
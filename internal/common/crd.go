@@ -0,0 +1,388 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// CRDConfig declares the custom resources to map and how they relate to other objects
+type CRDConfig struct {
+	Resources []CustomResourceRule `json:"resources"`
+}
+
+// CustomResourceRule describes one custom resource type
+type CustomResourceRule struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	// Selector is a JSONPath to a label selector choosing the pods the resource manages
+	Selector string `json:"selector,omitempty"`
+	// References are JSONPaths to names of other objects in the same namespace
+	References []ReferenceRule `json:"references,omitempty"`
+}
+
+// ReferenceRule points at fields holding object names, such as spec.secretName
+type ReferenceRule struct {
+	Path string `json:"path"`
+	// Kind of the referenced object; objects with name and kind fields may omit it
+	Kind string `json:"kind,omitempty"`
+}
+
+func (r CustomResourceRule) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+// LoadCRDConfig reads a custom resource config from a YAML or JSON file
+func LoadCRDConfig(path string) (*CRDConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading crd config: %v", err)
+	}
+
+	var config CRDConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing crd config: %v", err)
+	}
+
+	for i, rule := range config.Resources {
+		if rule.Version == "" || rule.Resource == "" {
+			return nil, fmt.Errorf("resources[%d]: version and resource are required", i)
+		}
+		if rule.Selector != "" {
+			if _, err := parseJSONPath(rule.Selector); err != nil {
+				return nil, fmt.Errorf("resources[%d] selector: %v", i, err)
+			}
+		}
+		for j, reference := range rule.References {
+			if _, err := parseJSONPath(reference.Path); err != nil {
+				return nil, fmt.Errorf("resources[%d] references[%d]: %v", i, j, err)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// parseJSONPath accepts both kubectl style templates ({.spec.secretName}) and
+// plain field paths (spec.secretName)
+func parseJSONPath(path string) (*jsonpath.JSONPath, error) {
+	if !strings.Contains(path, "{") {
+		path = "{." + strings.TrimPrefix(path, ".") + "}"
+	}
+	parser := jsonpath.New("crd").AllowMissingKeys(true)
+	if err := parser.Parse(path); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// findJSONPath returns the values a JSONPath selects in an object
+func findJSONPath(object *unstructured.Unstructured, path string) []interface{} {
+	parser, err := parseJSONPath(path)
+	if err != nil {
+		return nil
+	}
+	results, err := parser.FindResults(object.Object)
+	if err != nil {
+		return nil
+	}
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				values = append(values, value.Interface())
+			}
+		}
+	}
+	return values
+}
+
+// customObject is a listed custom resource with the rule that declared it
+type customObject struct {
+	rule   CustomResourceRule
+	object *unstructured.Unstructured
+}
+
+// objectReference resolves a JSONPath value to a kind and name. Strings are
+// names; maps such as issuerRef contribute their name and kind fields.
+func objectReference(value interface{}, kind string) (string, string) {
+	switch v := value.(type) {
+	case string:
+		return kind, v
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		if refKind, ok := v["kind"].(string); ok && kind == "" {
+			kind = refKind
+		}
+		return kind, name
+	}
+	return kind, ""
+}
+
+// selectorFromValue builds a label selector from either a metav1.LabelSelector
+// shaped value or a plain label map
+func selectorFromValue(value interface{}) (labels.Selector, error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("selector is not an object")
+	}
+	if _, ok := fields["matchLabels"]; !ok {
+		if _, ok := fields["matchExpressions"]; !ok {
+			set := labels.Set{}
+			for key, v := range fields {
+				set[key] = fmt.Sprint(v)
+			}
+			return labels.SelectorFromSet(set), nil
+		}
+	}
+	var selector metav1.LabelSelector
+	if err := decodeUnstructured(&unstructured.Unstructured{Object: fields}, &selector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(&selector)
+}
+
+// ownedObjects indexes the namespace objects that carry ownerReferences by owner UID
+func (rp *ResourceProcessor) ownedObjects(namespace string, pods []corev1.Pod, custom []customObject) (map[types.UID][]workloadRef, error) {
+	owned := make(map[types.UID][]workloadRef)
+	add := func(kind string, object metav1.Object) {
+		for _, owner := range object.GetOwnerReferences() {
+			owned[owner.UID] = append(owned[owner.UID], workloadRef{Kind: kind, Name: object.GetName()})
+		}
+	}
+
+	list := metav1.ListOptions{}
+	deployments, err := rp.clientset.AppsV1().Deployments(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting deployments: %v", err)
+	}
+	for i := range deployments.Items {
+		add("Deployment", &deployments.Items[i])
+	}
+	statefulSets, err := rp.clientset.AppsV1().StatefulSets(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting statefulsets: %v", err)
+	}
+	for i := range statefulSets.Items {
+		add("StatefulSet", &statefulSets.Items[i])
+	}
+	daemonSets, err := rp.clientset.AppsV1().DaemonSets(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting daemonsets: %v", err)
+	}
+	for i := range daemonSets.Items {
+		add("DaemonSet", &daemonSets.Items[i])
+	}
+	jobs, err := rp.clientset.BatchV1().Jobs(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting jobs: %v", err)
+	}
+	for i := range jobs.Items {
+		add("Job", &jobs.Items[i])
+	}
+	for i := range pods {
+		add("Pod", &pods[i])
+	}
+	services, err := rp.clientset.CoreV1().Services(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting services: %v", err)
+	}
+	for i := range services.Items {
+		add("Service", &services.Items[i])
+	}
+	configMaps, err := rp.clientset.CoreV1().ConfigMaps(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting configmaps: %v", err)
+	}
+	for i := range configMaps.Items {
+		add("ConfigMap", &configMaps.Items[i])
+	}
+	secrets, err := rp.clientset.CoreV1().Secrets(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets: %v", err)
+	}
+	for i := range secrets.Items {
+		add("Secret", &secrets.Items[i])
+	}
+	claims, err := rp.clientset.CoreV1().PersistentVolumeClaims(namespace).List(rp.ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("error getting persistentvolumeclaims: %v", err)
+	}
+	for i := range claims.Items {
+		add("PersistentVolumeClaim", &claims.Items[i])
+	}
+	for _, c := range custom {
+		add(c.object.GetKind(), c.object)
+	}
+	return owned, nil
+}
+
+// referenceExists reports whether a referenced object exists, and whether its
+// kind could be checked at all
+func (rp *ResourceProcessor) referenceExists(namespace, kind, name string, custom []customObject) (bool, bool) {
+	var err error
+	get := metav1.GetOptions{}
+	switch kind {
+	case "Secret":
+		_, err = rp.clientset.CoreV1().Secrets(namespace).Get(rp.ctx, name, get)
+	case "ConfigMap":
+		_, err = rp.clientset.CoreV1().ConfigMaps(namespace).Get(rp.ctx, name, get)
+	case "Service":
+		_, err = rp.clientset.CoreV1().Services(namespace).Get(rp.ctx, name, get)
+	case "ServiceAccount":
+		_, err = rp.clientset.CoreV1().ServiceAccounts(namespace).Get(rp.ctx, name, get)
+	case "PersistentVolumeClaim":
+		_, err = rp.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(rp.ctx, name, get)
+	default:
+		known := false
+		for _, c := range custom {
+			if c.object.GetKind() == kind {
+				known = true
+				if c.object.GetName() == name {
+					return true, true
+				}
+			}
+		}
+		return false, known
+	}
+	return err == nil, true
+}
+
+// ShowCustomResources maps the custom resources declared in the CRD config
+// through their ownerReferences, pod selectors and reference rules
+func (rp *ResourceProcessor) ShowCustomResources(namespace string) error {
+	config := rp.options.CustomResources
+	if config == nil || rp.dynamic == nil {
+		return nil
+	}
+	fmt.Println("\n[Custom Resource Layer]")
+
+	var objects []customObject
+	for _, rule := range config.Resources {
+		gvr := rule.gvr()
+		resource := rp.lookupResource(gvr)
+		if resource == nil {
+			rp.formatter.PrintWarning("%s (%s) is not served by the cluster", gvr.Resource, gvr.GroupVersion())
+			continue
+		}
+		if !resource.Namespaced {
+			rp.formatter.PrintWarning("%s (%s) is cluster-scoped and not mapped per namespace", gvr.Resource, gvr.GroupVersion())
+			continue
+		}
+		items, err := rp.listDynamic(gvr, namespace)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			objects = append(objects, customObject{rule: rule, object: &items[i]})
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+	owned, err := rp.ownedObjects(namespace, pods.Items, objects)
+	if err != nil {
+		return err
+	}
+	resolver := rp.newOwnerResolver(namespace)
+
+	for i, c := range objects {
+		isLast := i == len(objects)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		object := c.object
+		rp.formatter.PrintResource(prefix, object.GetKind(), object.GetName())
+		rp.formatter.Indent()
+
+		rp.formatter.PrintInfo("", "API: %s", object.GetAPIVersion())
+		var status struct {
+			Status struct {
+				Conditions []metav1.Condition `json:"conditions"`
+			} `json:"status"`
+		}
+		if decodeUnstructured(object, &status) == nil {
+			if condition := meta.FindStatusCondition(status.Status.Conditions, "Ready"); condition != nil {
+				text := fmt.Sprintf("Ready: %s", condition.Status)
+				if condition.Status != metav1.ConditionTrue && condition.Reason != "" {
+					text += fmt.Sprintf(" (%s)", condition.Reason)
+				}
+				rp.formatter.PrintStatus(text, condition.Status == metav1.ConditionTrue)
+			}
+		}
+
+		for _, owner := range object.GetOwnerReferences() {
+			rp.formatter.PrintInfo("", "Owned by: %s/%s", owner.Kind, owner.Name)
+		}
+
+		for _, reference := range c.rule.References {
+			for _, value := range findJSONPath(object, reference.Path) {
+				kind, name := objectReference(value, reference.Kind)
+				if name == "" {
+					continue
+				}
+				if kind == "" {
+					kind = "Object"
+				}
+				rp.formatter.PrintRelation(kind, name, fmt.Sprintf("via %s", reference.Path))
+				if exists, checked := rp.referenceExists(namespace, kind, name, objects); checked && !exists {
+					rp.formatter.PrintWarning("%s %s not found", kind, name)
+				}
+			}
+		}
+
+		for _, child := range owned[object.GetUID()] {
+			rp.formatter.PrintRelation(child.Kind, child.Name, "Owned")
+		}
+
+		if c.rule.Selector != "" {
+			for _, value := range findJSONPath(object, c.rule.Selector) {
+				selector, err := selectorFromValue(value)
+				if err != nil {
+					rp.formatter.PrintWarning("Invalid selector at %s: %v", c.rule.Selector, err)
+					continue
+				}
+				var selected []corev1.Pod
+				for _, pod := range pods.Items {
+					if selector.Matches(labels.Set(pod.Labels)) {
+						selected = append(selected, pod)
+					}
+				}
+				if len(selected) == 0 {
+					rp.formatter.PrintWarning("Selector %s matches no pods", selector.String())
+				}
+				for _, group := range resolver.groupPodsByOwner(selected) {
+					ready := 0
+					for _, pod := range group.pods {
+						if isPodReady(pod) {
+							ready++
+						}
+					}
+					rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name,
+						fmt.Sprintf("Selected: %d pods (%d ready)", len(group.pods), ready))
+				}
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
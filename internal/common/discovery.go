@@ -0,0 +1,76 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// lookupResource returns the discovery entry of a resource, or nil when the API
+// server does not serve it
+func (rp *ResourceProcessor) lookupResource(gvr schema.GroupVersionResource) *metav1.APIResource {
+	if rp.dynamic == nil {
+		return nil
+	}
+	if rp.discovered == nil {
+		rp.discovered = make(map[string]*metav1.APIResourceList)
+	}
+
+	groupVersion := gvr.GroupVersion().String()
+	resources, ok := rp.discovered[groupVersion]
+	if !ok {
+		// A missing group version is cached as nil
+		resources, _ = rp.clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
+		rp.discovered[groupVersion] = resources
+	}
+	if resources == nil {
+		return nil
+	}
+	for i := range resources.APIResources {
+		if resources.APIResources[i].Name == gvr.Resource {
+			return &resources.APIResources[i]
+		}
+	}
+	return nil
+}
+
+// findResource returns the first of the given versions in which the API server
+// serves a resource, so optional APIs are only queried when installed
+func (rp *ResourceProcessor) findResource(group, resource string, versions ...string) (schema.GroupVersionResource, bool) {
+	for _, version := range versions {
+		gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+		if rp.lookupResource(gvr) != nil {
+			return gvr, true
+		}
+	}
+	return schema.GroupVersionResource{}, false
+}
+
+// listDynamic lists a resource through the dynamic client; an empty namespace
+// lists cluster-scoped resources
+func (rp *ResourceProcessor) listDynamic(gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	var list *unstructured.UnstructuredList
+	var err error
+	if namespace == "" {
+		list, err = rp.dynamic.Resource(gvr).List(rp.ctx, metav1.ListOptions{})
+	} else {
+		list, err = rp.dynamic.Resource(gvr).Namespace(namespace).List(rp.ctx, metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %v", gvr.Resource, err)
+	}
+	return list.Items, nil
+}
+
+// decodeUnstructured converts an unstructured object into a typed view of the
+// fields a layer needs
+func decodeUnstructured(object *unstructured.Unstructured, into interface{}) error {
+	data, err := json.Marshal(object.Object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
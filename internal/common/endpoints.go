@@ -0,0 +1,237 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceEndpoint merges the addresses a backend has across the EndpointSlices
+// of each address family
+type serviceEndpoint struct {
+	target      string
+	addresses   []string
+	ready       bool
+	serving     bool
+	terminating bool
+	zone        string
+	node        string
+	hints       []string
+}
+
+// state describes the conditions of an endpoint
+func (e *serviceEndpoint) state() string {
+	switch {
+	case e.terminating && e.serving:
+		return "terminating, serving"
+	case e.terminating:
+		return "terminating"
+	case e.ready:
+		return "ready"
+	case e.serving:
+		return "not ready, serving"
+	}
+	return "not ready"
+}
+
+// collectEndpoints merges the endpoints of a Service's slices by target pod,
+// returning them with the address families and ports the slices carry
+func collectEndpoints(slices []discoveryv1.EndpointSlice) ([]*serviceEndpoint, []string, []string) {
+	var endpoints []*serviceEndpoint
+	byTarget := make(map[string]*serviceEndpoint)
+	families := make(map[string]bool)
+	ports := make(map[string]bool)
+
+	for _, slice := range slices {
+		families[string(slice.AddressType)] = true
+		for _, port := range slice.Ports {
+			description := ""
+			if port.Port != nil {
+				description = fmt.Sprintf("%d", *port.Port)
+			}
+			if port.Name != nil && *port.Name != "" {
+				description = fmt.Sprintf("%s=%s", *port.Name, description)
+			}
+			if port.Protocol != nil {
+				description += "/" + string(*port.Protocol)
+			}
+			ports[description] = true
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			key := strings.Join(endpoint.Addresses, ",")
+			target := ""
+			if endpoint.TargetRef != nil {
+				target = fmt.Sprintf("%s: %s", endpoint.TargetRef.Kind, endpoint.TargetRef.Name)
+				key = target
+			}
+
+			merged, ok := byTarget[key]
+			if !ok {
+				// Unset ready and serving mean the state is unknown and should be
+				// treated as ready
+				conditions := endpoint.Conditions
+				ready := conditions.Ready == nil || *conditions.Ready
+				serving := ready
+				if conditions.Serving != nil {
+					serving = *conditions.Serving
+				}
+				merged = &serviceEndpoint{
+					target:      target,
+					ready:       ready,
+					serving:     serving,
+					terminating: conditions.Terminating != nil && *conditions.Terminating,
+				}
+				if endpoint.Zone != nil {
+					merged.zone = *endpoint.Zone
+				}
+				if endpoint.NodeName != nil {
+					merged.node = *endpoint.NodeName
+				}
+				if endpoint.Hints != nil {
+					for _, zone := range endpoint.Hints.ForZones {
+						merged.hints = append(merged.hints, zone.Name)
+					}
+				}
+				byTarget[key] = merged
+				endpoints = append(endpoints, merged)
+			}
+			merged.addresses = append(merged.addresses, endpoint.Addresses...)
+		}
+	}
+
+	return endpoints, sortedKeys(families), sortedKeys(ports)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadNodeZones returns the zones the cluster's nodes run in
+func (rp *ResourceProcessor) loadNodeZones() (map[string]bool, error) {
+	nodes, err := rp.clientset.CoreV1().Nodes().List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting nodes: %v", err)
+	}
+	zones := make(map[string]bool)
+	for _, node := range nodes.Items {
+		if zone := node.Labels[corev1.LabelTopologyZone]; zone != "" {
+			zones[zone] = true
+		}
+	}
+	return zones, nil
+}
+
+// showEndpointSlices prints the endpoints of a Service from its EndpointSlices
+// with their conditions, topology and address families. Zones in nodeZones
+// without a ready endpoint are counted as empty
+func (rp *ResourceProcessor) showEndpointSlices(namespace string, service *corev1.Service, nodeZones map[string]bool) {
+	slices, err := rp.clientset.DiscoveryV1().EndpointSlices(namespace).List(rp.ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", discoveryv1.LabelServiceName, service.Name),
+	})
+	if err != nil {
+		rp.formatter.PrintWarning("Could not fetch endpoint slices: %v", err)
+		return
+	}
+	if len(slices.Items) == 0 {
+		return
+	}
+
+	endpoints, families, ports := collectEndpoints(slices.Items)
+	if len(endpoints) == 0 {
+		rp.formatter.PrintStatus("Endpoints: none", false)
+		return
+	}
+
+	ready, notReady, terminating := 0, 0, 0
+	readyByZone := make(map[string]int)
+	for _, endpoint := range endpoints {
+		switch {
+		case endpoint.terminating:
+			terminating++
+		case endpoint.ready:
+			ready++
+			if endpoint.zone != "" {
+				readyByZone[endpoint.zone]++
+			}
+		default:
+			notReady++
+		}
+	}
+
+	summary := fmt.Sprintf("Endpoints: %d ready", ready)
+	if notReady > 0 {
+		summary += fmt.Sprintf(", %d not ready", notReady)
+	}
+	if terminating > 0 {
+		summary += fmt.Sprintf(", %d terminating", terminating)
+	}
+	rp.formatter.PrintInfo("", "%s (%s)", summary, strings.Join(families, ", "))
+	if len(ports) > 0 {
+		rp.formatter.PrintInfo("", "  Ports: %s", strings.Join(ports, ", "))
+	}
+
+	for _, endpoint := range endpoints {
+		line := strings.Join(endpoint.addresses, ", ")
+		if endpoint.target != "" {
+			line += fmt.Sprintf(" (%s)", endpoint.target)
+		}
+		line += " " + endpoint.state()
+		if endpoint.zone != "" {
+			line += fmt.Sprintf(", zone: %s", endpoint.zone)
+		}
+		if endpoint.node != "" {
+			line += fmt.Sprintf(", node: %s", endpoint.node)
+		}
+		if len(endpoint.hints) > 0 {
+			line += fmt.Sprintf(", hints: %s", strings.Join(endpoint.hints, ","))
+		}
+		rp.formatter.PrintStatus("  "+line, endpoint.ready && !endpoint.terminating)
+	}
+
+	if ready == 0 {
+		return
+	}
+	for zone := range nodeZones {
+		if _, ok := readyByZone[zone]; !ok {
+			readyByZone[zone] = 0
+		}
+	}
+	if len(readyByZone) > 1 {
+		zones := make([]string, 0, len(readyByZone))
+		for zone := range readyByZone {
+			zones = append(zones, zone)
+		}
+		sort.Strings(zones)
+		least, most := -1, 0
+		var empty []string
+		for i, zone := range zones {
+			count := readyByZone[zone]
+			zones[i] = fmt.Sprintf("%s=%d", zone, count)
+			if least < 0 || count < least {
+				least = count
+			}
+			most = max(most, count)
+			if count == 0 {
+				empty = append(empty, zone)
+			}
+		}
+		rp.formatter.PrintInfo("", "  Ready by zone: %s", strings.Join(zones, ", "))
+		// An odd replica count always leaves one zone ahead by one
+		switch {
+		case len(empty) > 0:
+			rp.formatter.PrintWarning("No ready endpoints in zone(s) with nodes: %s", strings.Join(empty, ", "))
+		case most-least > 1:
+			rp.formatter.PrintWarning("Ready endpoints are unevenly spread across zones")
+		}
+	}
+}
@@ -54,6 +54,10 @@ func (f *Formatter) PrintStatus(status string, ok bool) {
 	fmt.Printf("%s%s%s %s%s\n", f.getIndent(), color, icon, status, ColorReset)
 }
 
+func (f *Formatter) PrintWarning(format string, a ...interface{}) {
+	fmt.Printf("%s%s⚠ %s%s\n", f.getIndent(), ColorYellow, fmt.Sprintf(format, a...), ColorReset)
+}
+
 func (f *Formatter) PrintRelation(resourceType, name string, details ...string) {
 	fmt.Printf("%s➜ %s/%s%s\n", f.getIndent(), resourceType, name, ColorReset)
 	for _, detail := range details {
@@ -0,0 +1,537 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// gatewayRouteKinds are the route kinds shown under Gateways, with the API
+// versions to look for in order of preference
+var gatewayRouteKinds = []struct {
+	kind     string
+	resource string
+	versions []string
+}{
+	{"HTTPRoute", "httproutes", []string{"v1", "v1beta1"}},
+	{"GRPCRoute", "grpcroutes", []string{"v1", "v1alpha2"}},
+	{"TLSRoute", "tlsroutes", []string{"v1alpha3", "v1alpha2"}},
+}
+
+// The types below are the subset of the Gateway API schema microlens renders,
+// decoded from unstructured objects so no Gateway API module is required
+
+type gatewayClass struct {
+	Spec struct {
+		ControllerName string `json:"controllerName"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []metav1.Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+type gateway struct {
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		GatewayClassName string            `json:"gatewayClassName"`
+		Listeners        []gatewayListener `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Value string `json:"value"`
+		} `json:"addresses"`
+		Conditions []metav1.Condition `json:"conditions"`
+		Listeners  []struct {
+			Name           string `json:"name"`
+			AttachedRoutes int32  `json:"attachedRoutes"`
+		} `json:"listeners"`
+	} `json:"status"`
+}
+
+type gatewayListener struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+	TLS      *struct {
+		Mode            string `json:"mode"`
+		CertificateRefs []struct {
+			Kind      string `json:"kind"`
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"certificateRefs"`
+	} `json:"tls"`
+}
+
+type parentReference struct {
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName"`
+	Port        int32  `json:"port"`
+}
+
+type gatewayRoute struct {
+	kind     string
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		ParentRefs []parentReference `json:"parentRefs"`
+		Hostnames  []string          `json:"hostnames"`
+		Rules      []routeRule       `json:"rules"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef      parentReference    `json:"parentRef"`
+			ControllerName string             `json:"controllerName"`
+			Conditions     []metav1.Condition `json:"conditions"`
+		} `json:"parents"`
+	} `json:"status"`
+}
+
+type routeRule struct {
+	Matches     []routeMatch `json:"matches"`
+	BackendRefs []struct {
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		Port      int32  `json:"port"`
+		Weight    *int32 `json:"weight"`
+	} `json:"backendRefs"`
+}
+
+type routeMatch struct {
+	Path *struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"path"`
+	Headers     []routeMatchValue `json:"headers"`
+	QueryParams []routeMatchValue `json:"queryParams"`
+	Method      routeMethod       `json:"method"`
+}
+
+type routeMatchValue struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// routeMethod is an HTTP method for HTTPRoutes and a service/method pair for GRPCRoutes
+type routeMethod string
+
+func (m *routeMethod) UnmarshalJSON(data []byte) error {
+	var method string
+	if err := json.Unmarshal(data, &method); err == nil {
+		*m = routeMethod(method)
+		return nil
+	}
+	var grpc struct {
+		Service string `json:"service"`
+		Method  string `json:"method"`
+	}
+	if err := json.Unmarshal(data, &grpc); err != nil {
+		return err
+	}
+	switch {
+	case grpc.Service != "" && grpc.Method != "":
+		*m = routeMethod(grpc.Service + "/" + grpc.Method)
+	case grpc.Service != "":
+		*m = routeMethod(grpc.Service + "/*")
+	case grpc.Method != "":
+		*m = routeMethod("*/" + grpc.Method)
+	}
+	return nil
+}
+
+func describeRouteMatch(match routeMatch) string {
+	var parts []string
+	if match.Path != nil {
+		parts = append(parts, fmt.Sprintf("path %s %s", match.Path.Type, match.Path.Value))
+	}
+	if match.Method != "" {
+		parts = append(parts, fmt.Sprintf("method %s", match.Method))
+	}
+	for _, header := range match.Headers {
+		parts = append(parts, fmt.Sprintf("header %s=%s", header.Name, header.Value))
+	}
+	for _, query := range match.QueryParams {
+		parts = append(parts, fmt.Sprintf("query %s=%s", query.Name, query.Value))
+	}
+	if len(parts) == 0 {
+		return "all requests"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// attachesTo reports whether a route parentRef points at the given Gateway
+func (ref parentReference) attachesTo(routeNamespace string, gw *gateway) bool {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = routeNamespace
+	}
+	return (ref.Kind == "" || ref.Kind == "Gateway") && ref.Name == gw.Metadata.Name && namespace == gw.Metadata.Namespace
+}
+
+func (ref parentReference) String() string {
+	kind := ref.Kind
+	if kind == "" {
+		kind = "Gateway"
+	}
+	s := kind + "/" + ref.Name
+	if ref.Namespace != "" {
+		s = kind + "/" + ref.Namespace + "/" + ref.Name
+	}
+	if ref.SectionName != "" {
+		s += " (" + ref.SectionName + ")"
+	}
+	return s
+}
+
+// conditionStatus describes a condition as ok or not, with the reason when it is not
+func conditionStatus(conditions []metav1.Condition, conditionType string) (string, bool, bool) {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		return "", false, false
+	}
+	if condition.Status == metav1.ConditionTrue {
+		return conditionType, true, true
+	}
+	text := fmt.Sprintf("%s: %s", conditionType, condition.Status)
+	if condition.Reason != "" {
+		text += fmt.Sprintf(" (%s)", condition.Reason)
+	}
+	if condition.Message != "" {
+		text += " " + condition.Message
+	}
+	return text, false, true
+}
+
+// loadGatewayRoutes lists the routes of every installed route kind in a
+// namespace, or in all namespaces when it is empty
+func (rp *ResourceProcessor) loadGatewayRoutes(namespace string) ([]*gatewayRoute, error) {
+	var routes []*gatewayRoute
+	for _, routeKind := range gatewayRouteKinds {
+		gvr, ok := rp.findResource(gatewayAPIGroup, routeKind.resource, routeKind.versions...)
+		if !ok {
+			continue
+		}
+		items, err := rp.listDynamic(gvr, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			route := &gatewayRoute{kind: routeKind.kind}
+			if err := decodeUnstructured(&items[i], route); err != nil {
+				return nil, fmt.Errorf("error decoding %s %s: %v", routeKind.kind, items[i].GetName(), err)
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// loadGateways lists the Gateways of a namespace, or of all namespaces when it is empty
+func (rp *ResourceProcessor) loadGateways(gvr schema.GroupVersionResource, namespace string) ([]*gateway, error) {
+	items, err := rp.listDynamic(gvr, namespace)
+	if err != nil {
+		return nil, err
+	}
+	gateways := make([]*gateway, 0, len(items))
+	for i := range items {
+		gw := &gateway{}
+		if err := decodeUnstructured(&items[i], gw); err != nil {
+			return nil, fmt.Errorf("error decoding Gateway %s: %v", items[i].GetName(), err)
+		}
+		gateways = append(gateways, gw)
+	}
+	return gateways, nil
+}
+
+// ShowGatewayDetails shows Gateway API resources when they are installed:
+// GatewayClass → Gateway listeners → HTTPRoute/GRPCRoute/TLSRoute rules → backends
+func (rp *ResourceProcessor) ShowGatewayDetails(namespace string) error {
+	gatewayGVR, ok := rp.findResource(gatewayAPIGroup, "gateways", "v1", "v1beta1")
+	if !ok {
+		return nil
+	}
+	fmt.Println("\n[Gateway API Layer]")
+
+	// Routes may attach to a shared Gateway of another namespace, so Gateways
+	// and routes are listed in all namespaces when readable
+	allGateways, err := rp.loadGateways(gatewayGVR, "")
+	if err != nil {
+		rp.formatter.PrintWarning("Could not list Gateways in all namespaces, showing %s only: %v", namespace, err)
+		if allGateways, err = rp.loadGateways(gatewayGVR, namespace); err != nil {
+			return err
+		}
+	}
+	allRoutes, err := rp.loadGatewayRoutes("")
+	if err != nil {
+		rp.formatter.PrintWarning("Could not list routes in all namespaces, showing %s only: %v", namespace, err)
+		if allRoutes, err = rp.loadGatewayRoutes(namespace); err != nil {
+			return err
+		}
+	}
+	var gateways []*gateway
+	for _, gw := range allGateways {
+		if gw.Metadata.Namespace == namespace {
+			gateways = append(gateways, gw)
+		}
+	}
+
+	services, err := rp.clientset.CoreV1().Services(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting services: %v", err)
+	}
+	serviceNames := map[string]map[string]bool{namespace: {}}
+	for _, service := range services.Items {
+		serviceNames[namespace][service.Name] = true
+	}
+	// servicesIn returns the Service names of a route's namespace, or nil when
+	// they are not readable
+	servicesIn := func(ns string) map[string]bool {
+		if names, ok := serviceNames[ns]; ok {
+			return names
+		}
+		var names map[string]bool
+		if list, err := rp.clientset.CoreV1().Services(ns).List(rp.ctx, metav1.ListOptions{}); err == nil {
+			names = make(map[string]bool)
+			for _, service := range list.Items {
+				names[service.Name] = true
+			}
+		}
+		serviceNames[ns] = names
+		return names
+	}
+
+	// Routes of any namespace attached to a Gateway here are nested under it;
+	// the other routes of this namespace are listed on their own with their
+	// parents
+	var routes, standalone []*gatewayRoute
+	for _, route := range allRoutes {
+		local := route.Metadata.Namespace == namespace
+		attached := false
+		for _, ref := range route.Spec.ParentRefs {
+			for _, gw := range gateways {
+				if ref.attachesTo(route.Metadata.Namespace, gw) {
+					attached = true
+				}
+			}
+		}
+		if attached {
+			routes = append(routes, route)
+		}
+		if local && !attached {
+			standalone = append(standalone, route)
+		}
+	}
+
+	classes := make(map[string]*gatewayClass)
+	classGVR, classesServed := rp.findResource(gatewayAPIGroup, "gatewayclasses", gatewayGVR.Version)
+	if classesServed {
+		// GatewayClasses are cluster-scoped and only shown when readable
+		if items, err := rp.listDynamic(classGVR, ""); err == nil {
+			for i := range items {
+				class := &gatewayClass{}
+				if decodeUnstructured(&items[i], class) == nil {
+					classes[items[i].GetName()] = class
+				}
+			}
+		}
+	}
+
+	total := len(gateways) + len(standalone)
+	index := 0
+	nextPrefix := func() string {
+		index++
+		if index == total {
+			return "└──"
+		}
+		return "├──"
+	}
+
+	for _, gw := range gateways {
+		rp.formatter.PrintResource(nextPrefix(), "Gateway", gw.Metadata.Name)
+		rp.formatter.Indent()
+
+		var classDetails []string
+		if class, ok := classes[gw.Spec.GatewayClassName]; ok {
+			classDetails = append(classDetails, fmt.Sprintf("Controller: %s", class.Spec.ControllerName))
+			if text, ok, found := conditionStatus(class.Status.Conditions, "Accepted"); found && !ok {
+				classDetails = append(classDetails, text)
+			}
+		}
+		rp.formatter.PrintRelation("GatewayClass", gw.Spec.GatewayClassName, classDetails...)
+
+		if text, ok, found := conditionStatus(gw.Status.Conditions, "Programmed"); found {
+			rp.formatter.PrintStatus(text, ok)
+		}
+		if len(gw.Status.Addresses) > 0 {
+			var addresses []string
+			for _, address := range gw.Status.Addresses {
+				addresses = append(addresses, address.Value)
+			}
+			rp.formatter.PrintInfo("", "Addresses: %s", strings.Join(addresses, ", "))
+		}
+
+		attachedRoutes := make(map[string]int32)
+		for _, listener := range gw.Status.Listeners {
+			attachedRoutes[listener.Name] = listener.AttachedRoutes
+		}
+		for _, listener := range gw.Spec.Listeners {
+			hostname := "*"
+			if listener.Hostname != "" {
+				hostname = listener.Hostname
+			}
+			rp.formatter.PrintInfo("", "Listener %s: %s %s:%d (attached routes: %d)",
+				listener.Name, listener.Protocol, hostname, listener.Port, attachedRoutes[listener.Name])
+			if listener.TLS != nil {
+				var certificates []string
+				for _, ref := range listener.TLS.CertificateRefs {
+					certificates = append(certificates, ref.Name)
+				}
+				mode := listener.TLS.Mode
+				if mode == "" {
+					mode = "Terminate"
+				}
+				rp.formatter.PrintInfo("", "  TLS %s: %s", mode, strings.Join(certificates, ", "))
+			}
+		}
+
+		for _, route := range routes {
+			var sections []string
+			attachedHere := false
+			for _, ref := range route.Spec.ParentRefs {
+				if ref.attachesTo(route.Metadata.Namespace, gw) {
+					attachedHere = true
+					if ref.SectionName != "" {
+						sections = append(sections, ref.SectionName)
+					}
+				}
+			}
+			if !attachedHere {
+				continue
+			}
+			var details []string
+			if len(sections) > 0 {
+				details = append(details, fmt.Sprintf("Listeners: %s", strings.Join(sections, ", ")))
+			}
+			name := route.Metadata.Name
+			if route.Metadata.Namespace != namespace {
+				name = route.Metadata.Namespace + "/" + name
+			}
+			rp.formatter.PrintRelation(route.kind, name, details...)
+			rp.formatter.Indent()
+			rp.showGatewayRoute(route, servicesIn(route.Metadata.Namespace))
+			rp.formatter.Outdent()
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	for _, route := range standalone {
+		rp.formatter.PrintResource(nextPrefix(), route.kind, route.Metadata.Name)
+		rp.formatter.Indent()
+		for _, ref := range route.Spec.ParentRefs {
+			if ref.Kind != "" && ref.Kind != "Gateway" {
+				rp.formatter.PrintInfo("", "Parent: %s", ref)
+				continue
+			}
+			var parent *gateway
+			for _, gw := range allGateways {
+				if ref.attachesTo(route.Metadata.Namespace, gw) {
+					parent = gw
+				}
+			}
+			if parent == nil {
+				rp.formatter.PrintWarning("Parent %s not found", ref)
+				continue
+			}
+			var details []string
+			if ref.SectionName != "" {
+				details = append(details, fmt.Sprintf("Listeners: %s", ref.SectionName))
+			}
+			rp.formatter.PrintRelation("Gateway", parent.Metadata.Namespace+"/"+parent.Metadata.Name, details...)
+		}
+		rp.showGatewayRoute(route, servicesIn(route.Metadata.Namespace))
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+// showGatewayRoute prints the hostnames, parent status and rules of a route.
+// Backends missing from serviceNames are flagged unless it is nil
+func (rp *ResourceProcessor) showGatewayRoute(route *gatewayRoute, serviceNames map[string]bool) {
+	if len(route.Spec.Hostnames) > 0 {
+		rp.formatter.PrintInfo("", "Hostnames: %s", strings.Join(route.Spec.Hostnames, ", "))
+	}
+
+	if len(route.Status.Parents) == 0 {
+		rp.formatter.PrintWarning("No parent has reported status for this route")
+	}
+	for _, parent := range route.Status.Parents {
+		for _, conditionType := range []string{"Accepted", "ResolvedRefs"} {
+			if text, ok, found := conditionStatus(parent.Conditions, conditionType); found {
+				rp.formatter.PrintStatus(fmt.Sprintf("%s: %s", parent.ParentRef, text), ok)
+			}
+		}
+	}
+
+	for i, rule := range route.Spec.Rules {
+		var matches []string
+		for _, match := range rule.Matches {
+			matches = append(matches, describeRouteMatch(match))
+		}
+		if len(matches) == 0 {
+			matches = append(matches, describeRouteMatch(routeMatch{}))
+		}
+		rp.formatter.PrintInfo("", "Rule %d: %s", i+1, strings.Join(matches, " or "))
+		rp.formatter.Indent()
+
+		var totalWeight int32
+		for _, backend := range rule.BackendRefs {
+			if backend.Weight == nil {
+				totalWeight++
+			} else {
+				totalWeight += *backend.Weight
+			}
+		}
+		for _, backend := range rule.BackendRefs {
+			kind := backend.Kind
+			if kind == "" {
+				kind = "Service"
+			}
+			var details []string
+			if backend.Port > 0 {
+				details = append(details, fmt.Sprintf("Port: %d", backend.Port))
+			}
+			if len(rule.BackendRefs) > 1 {
+				var weight int32 = 1
+				if backend.Weight != nil {
+					weight = *backend.Weight
+				}
+				share := 0
+				if totalWeight > 0 {
+					share = int(weight * 100 / totalWeight)
+				}
+				details = append(details, fmt.Sprintf("Weight: %d (%d%%)", weight, share))
+			}
+			name := backend.Name
+			if backend.Namespace != "" && backend.Namespace != route.Metadata.Namespace {
+				name = backend.Namespace + "/" + backend.Name
+			}
+			rp.formatter.PrintRelation(kind, name, details...)
+			if kind == "Service" && name == backend.Name && serviceNames != nil && !serviceNames[backend.Name] {
+				rp.formatter.PrintWarning("Service %s not found", backend.Name)
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+}
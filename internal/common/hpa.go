@@ -0,0 +1,416 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// hpaDefaultBehavior is what the autoscaler applies to a direction without
+// configured behavior
+var hpaDefaultBehavior = map[string]autoscalingv2.HPAScalingRules{
+	"Up": {
+		StabilizationWindowSeconds: int32Ptr(0),
+		SelectPolicy:               selectPolicyPtr(autoscalingv2.MaxChangePolicySelect),
+		Policies: []autoscalingv2.HPAScalingPolicy{
+			{Type: autoscalingv2.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+			{Type: autoscalingv2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+		},
+	},
+	"Down": {
+		StabilizationWindowSeconds: int32Ptr(300),
+		SelectPolicy:               selectPolicyPtr(autoscalingv2.MaxChangePolicySelect),
+		Policies: []autoscalingv2.HPAScalingPolicy{
+			{Type: autoscalingv2.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+		},
+	},
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func selectPolicyPtr(policy autoscalingv2.ScalingPolicySelect) *autoscalingv2.ScalingPolicySelect {
+	return &policy
+}
+
+// hpaMinReplicas returns the minimum replicas of an HPA, which default to 1
+func hpaMinReplicas(hpa *autoscalingv2.HorizontalPodAutoscaler) (int32, bool) {
+	if hpa.Spec.MinReplicas == nil {
+		return 1, true
+	}
+	return *hpa.Spec.MinReplicas, false
+}
+
+// describeMetricTarget renders the target of a metric, e.g. "70% average utilization"
+func describeMetricTarget(target autoscalingv2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%% average utilization", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return fmt.Sprintf("average value %s", target.AverageValue.String())
+	case target.Value != nil:
+		return fmt.Sprintf("value %s", target.Value.String())
+	}
+	return "none"
+}
+
+// describeMetricValue renders the current value of a metric in the form of its target
+func describeMetricValue(value autoscalingv2.MetricValueStatus) string {
+	switch {
+	case value.AverageUtilization != nil && value.AverageValue != nil:
+		return fmt.Sprintf("%d%% average utilization (%s)", *value.AverageUtilization, value.AverageValue.String())
+	case value.AverageUtilization != nil:
+		return fmt.Sprintf("%d%% average utilization", *value.AverageUtilization)
+	case value.AverageValue != nil:
+		return fmt.Sprintf("average value %s", value.AverageValue.String())
+	case value.Value != nil:
+		return fmt.Sprintf("value %s", value.Value.String())
+	}
+	return "unknown"
+}
+
+// describeMetricSelector renders a metric label selector, or "" when unset
+func describeMetricSelector(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (selector: %s)", metav1.FormatLabelSelector(selector))
+}
+
+// describeMetricSpec returns the description and target of a metric spec; with
+// the metric type, the description identifies the entry of status.currentMetrics
+func describeMetricSpec(metric autoscalingv2.MetricSpec) (string, autoscalingv2.MetricTarget, bool) {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource != nil {
+			return string(metric.Resource.Name), metric.Resource.Target, true
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if metric.ContainerResource != nil {
+			source := metric.ContainerResource
+			return fmt.Sprintf("%s of container %s", source.Name, source.Container), source.Target, true
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods != nil {
+			source := metric.Pods
+			return fmt.Sprintf("%s%s", source.Metric.Name, describeMetricSelector(source.Metric.Selector)), source.Target, true
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object != nil {
+			source := metric.Object
+			return fmt.Sprintf("%s on %s/%s%s", source.Metric.Name,
+				source.DescribedObject.Kind, source.DescribedObject.Name, describeMetricSelector(source.Metric.Selector)), source.Target, true
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External != nil {
+			source := metric.External
+			return fmt.Sprintf("%s%s", source.Metric.Name, describeMetricSelector(source.Metric.Selector)), source.Target, true
+		}
+	}
+	return "", autoscalingv2.MetricTarget{}, false
+}
+
+// describeMetricStatus returns the description and current value of a metric
+// status, described the same way as its spec
+func describeMetricStatus(metric autoscalingv2.MetricStatus) (string, autoscalingv2.MetricValueStatus, bool) {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource != nil {
+			return string(metric.Resource.Name), metric.Resource.Current, true
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if metric.ContainerResource != nil {
+			source := metric.ContainerResource
+			return fmt.Sprintf("%s of container %s", source.Name, source.Container), source.Current, true
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods != nil {
+			source := metric.Pods
+			return fmt.Sprintf("%s%s", source.Metric.Name, describeMetricSelector(source.Metric.Selector)), source.Current, true
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object != nil {
+			source := metric.Object
+			return fmt.Sprintf("%s on %s/%s%s", source.Metric.Name,
+				source.DescribedObject.Kind, source.DescribedObject.Name, describeMetricSelector(source.Metric.Selector)), source.Current, true
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External != nil {
+			source := metric.External
+			return fmt.Sprintf("%s%s", source.Metric.Name, describeMetricSelector(source.Metric.Selector)), source.Current, true
+		}
+	}
+	return "", autoscalingv2.MetricValueStatus{}, false
+}
+
+// describeScalingRules renders the behavior of one scaling direction
+func describeScalingRules(rules autoscalingv2.HPAScalingRules) string {
+	if rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect {
+		return "disabled"
+	}
+
+	var parts []string
+	if rules.StabilizationWindowSeconds != nil {
+		parts = append(parts, fmt.Sprintf("stabilization %ds", *rules.StabilizationWindowSeconds))
+	}
+	var policies []string
+	for _, policy := range rules.Policies {
+		unit := " pods"
+		switch {
+		case policy.Type == autoscalingv2.PercentScalingPolicy:
+			unit = "%"
+		case policy.Value == 1:
+			unit = " pod"
+		}
+		policies = append(policies, fmt.Sprintf("%d%s per %ds", policy.Value, unit, policy.PeriodSeconds))
+	}
+	if len(policies) > 0 {
+		parts = append(parts, "policies: "+strings.Join(policies, ", "))
+	}
+	if rules.SelectPolicy != nil && len(rules.Policies) > 1 {
+		parts = append(parts, fmt.Sprintf("select %s", *rules.SelectPolicy))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// showHPABehavior prints the scale-up and scale-down behavior of an HPA,
+// filling directions without configured rules from the autoscaler defaults
+func (rp *ResourceProcessor) showHPABehavior(behavior *autoscalingv2.HorizontalPodAutoscalerBehavior) {
+	directions := []struct {
+		name  string
+		rules *autoscalingv2.HPAScalingRules
+	}{{"Up", nil}, {"Down", nil}}
+	if behavior != nil {
+		directions[0].rules = behavior.ScaleUp
+		directions[1].rules = behavior.ScaleDown
+	}
+
+	rp.formatter.PrintInfo("", "Behavior:")
+	for _, direction := range directions {
+		defaults := hpaDefaultBehavior[direction.name]
+		if direction.rules == nil {
+			rp.formatter.PrintInfo("", "  Scale %s: %s (default)", direction.name, describeScalingRules(defaults))
+			continue
+		}
+		// Unset fields of configured rules fall back to the defaults too
+		rules := *direction.rules
+		if rules.StabilizationWindowSeconds == nil {
+			rules.StabilizationWindowSeconds = defaults.StabilizationWindowSeconds
+		}
+		if rules.SelectPolicy == nil {
+			rules.SelectPolicy = defaults.SelectPolicy
+		}
+		if len(rules.Policies) == 0 {
+			rules.Policies = defaults.Policies
+		}
+		rp.formatter.PrintInfo("", "  Scale %s: %s", direction.name, describeScalingRules(rules))
+	}
+}
+
+// hpaConditionHealthy reports whether a condition status is the healthy one;
+// ScalingLimited is healthy when false
+func hpaConditionHealthy(condition autoscalingv2.HorizontalPodAutoscalerCondition) bool {
+	if condition.Type == autoscalingv2.ScalingLimited {
+		return condition.Status != corev1.ConditionTrue
+	}
+	return condition.Status == corev1.ConditionTrue
+}
+
+// defaultHPAUtilization is the average CPU utilization an HPA without
+// metrics scales on
+const defaultHPAUtilization = int32(80)
+
+// hpaMetrics returns the metrics of an HPA, or the CPU utilization metric the
+// autoscaler defaults to when none is set, reporting whether it was defaulted
+func hpaMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) ([]autoscalingv2.MetricSpec, bool) {
+	if len(hpa.Spec.Metrics) > 0 {
+		return hpa.Spec.Metrics, false
+	}
+	utilization := defaultHPAUtilization
+	return []autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   corev1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+		},
+	}}, true
+}
+
+// hpaLintRules are the lint rules checking HPAs, shown in the HPA layer;
+// hpaScalerRules are those comparing HPAs with each other, which need no
+// scale target
+var (
+	hpaLintRules = []string{"hpa-missing-target", "hpa-target-no-cpu-requests", "hpa-target-no-memory-requests",
+		"hpa-min-above-replicas", "hpa-multiple-scalers", "vpa-hpa-conflict"}
+	hpaScalerRules = []string{"hpa-multiple-scalers", "vpa-hpa-conflict"}
+)
+
+// describeAgainstTarget compares a current metric value with its target, e.g.
+// "121% of target"
+func describeAgainstTarget(target autoscalingv2.MetricTarget, value autoscalingv2.MetricValueStatus) string {
+	var ratio float64
+	switch {
+	case target.AverageUtilization != nil && value.AverageUtilization != nil && *target.AverageUtilization > 0:
+		ratio = float64(*value.AverageUtilization) / float64(*target.AverageUtilization)
+	case target.AverageValue != nil && value.AverageValue != nil && !target.AverageValue.IsZero():
+		ratio = value.AverageValue.AsApproximateFloat64() / target.AverageValue.AsApproximateFloat64()
+	case target.Value != nil && value.Value != nil && !target.Value.IsZero():
+		ratio = value.Value.AsApproximateFloat64() / target.Value.AsApproximateFloat64()
+	default:
+		return ""
+	}
+	return fmt.Sprintf("%.0f%% of target", ratio*100)
+}
+
+// ShowHPADetails prints the HPAs of a namespace and, when their CRDs are
+// installed, the KEDA ScaledObjects and ScaledJobs and the VPAs
+func (rp *ResourceProcessor) ShowHPADetails(namespace string) error {
+	fmt.Println("\n[HPA Layer]")
+	hpas, err := rp.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting HPAs: %v", err)
+	}
+	external := rp.loadAutoscalers(namespace)
+	for _, warning := range external.warnings {
+		rp.formatter.PrintWarning("%s", warning)
+	}
+	if len(hpas.Items) == 0 && external.empty() {
+		return nil
+	}
+	// Scale targets are resolved and validated by the lint rules; without
+	// them only the checks between HPAs run
+	rules := hpaLintRules
+	targets, err := loadWorkloadContext(rp.clientset, rp.ctx, namespace)
+	if err != nil {
+		rp.formatter.PrintWarning("Could not load scale targets, showing HPAs without target checks: %v", err)
+		targets = &lintContext{namespace: namespace}
+		rules = hpaScalerRules
+	}
+	targets.hpas = hpas.Items
+	targets.scaledObjects = external.scaledObjects
+	targets.vpas = external.vpas
+	lintFindings := runLintRules(targets, rules...)
+
+	hpaNames := make(map[string]bool)
+	for _, hpa := range hpas.Items {
+		hpaNames[hpa.Name] = true
+	}
+
+	// HPAs, ScaledObjects, ScaledJobs and VPAs share one tree
+	remaining := len(hpas.Items) + len(external.scaledObjects) + len(external.scaledJobs) + len(external.vpas)
+	nextPrefix := func() string {
+		remaining--
+		if remaining == 0 {
+			return "└──"
+		}
+		return "├──"
+	}
+
+	for _, hpa := range hpas.Items {
+		rp.formatter.PrintResource(nextPrefix(), "HPA", hpa.Name)
+		rp.formatter.Indent()
+
+		ref := workloadRef{Kind: hpa.Spec.ScaleTargetRef.Kind, Name: hpa.Spec.ScaleTargetRef.Name}
+		target := targets.findScaleTarget(ref.Kind, ref.Name)
+		if target != nil {
+			rp.formatter.PrintInfo("", "Target: %s (%d/%d replicas ready)", ref, target.ready, target.replicas)
+		} else {
+			rp.formatter.PrintInfo("", "Target: %s", ref)
+		}
+		if owner := scaledObjectOf(&hpa); owner != "" {
+			rp.formatter.PrintInfo("", "Managed by: ScaledObject/%s", owner)
+		}
+		if minReplicas, defaulted := hpaMinReplicas(&hpa); defaulted {
+			rp.formatter.PrintInfo("", "Min Replicas: %d (default)", minReplicas)
+		} else {
+			rp.formatter.PrintInfo("", "Min Replicas: %d", minReplicas)
+		}
+		rp.formatter.PrintInfo("", "Max Replicas: %d", hpa.Spec.MaxReplicas)
+
+		// Current values are matched to the spec metrics by type and description
+		current := make(map[string]autoscalingv2.MetricValueStatus)
+		for _, status := range hpa.Status.CurrentMetrics {
+			if description, value, ok := describeMetricStatus(status); ok {
+				current[string(status.Type)+" "+description] = value
+			}
+		}
+		metrics, defaulted := hpaMetrics(&hpa)
+		for _, spec := range metrics {
+			description, metricTarget, ok := describeMetricSpec(spec)
+			if !ok {
+				rp.formatter.PrintWarning("Metric of type %s has no source", spec.Type)
+				continue
+			}
+			if defaulted {
+				rp.formatter.PrintInfo("", "%s Metric: %s (default)", spec.Type, description)
+			} else {
+				rp.formatter.PrintInfo("", "%s Metric: %s", spec.Type, description)
+			}
+			rp.formatter.PrintInfo("", "  Target: %s", describeMetricTarget(metricTarget))
+			if value, ok := current[string(spec.Type)+" "+description]; ok {
+				line := describeMetricValue(value)
+				if comparison := describeAgainstTarget(metricTarget, value); comparison != "" {
+					line += ", " + comparison
+				}
+				rp.formatter.PrintInfo("", "  Current: %s", line)
+			} else if len(hpa.Status.Conditions) > 0 {
+				rp.formatter.PrintStatus("  Current: unavailable", false)
+			}
+		}
+
+		rp.showHPABehavior(hpa.Spec.Behavior)
+
+		if hpa.Status.CurrentReplicas > 0 {
+			rp.formatter.PrintInfo("", "Current Replicas: %d", hpa.Status.CurrentReplicas)
+			rp.formatter.PrintInfo("", "Desired Replicas: %d", hpa.Status.DesiredReplicas)
+		}
+		if hpa.Status.LastScaleTime != nil {
+			rp.formatter.PrintInfo("", "Last Scale: %s", formatTimestamp(hpa.Status.LastScaleTime))
+		}
+
+		for _, condition := range hpa.Status.Conditions {
+			line := fmt.Sprintf("%s: %s", condition.Type, condition.Status)
+			if condition.Reason != "" {
+				line += fmt.Sprintf(" (%s)", condition.Reason)
+			}
+			if condition.Message != "" {
+				line += ": " + condition.Message
+			}
+			rp.formatter.PrintStatus(line, hpaConditionHealthy(condition))
+		}
+
+		if findings := findingsFor(lintFindings, "HorizontalPodAutoscaler/"+hpa.Name); len(findings) > 0 {
+			rp.formatter.PrintInfo("", "Findings:")
+			printFindings(rp.formatter, findings)
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	for _, so := range external.scaledObjects {
+		rp.formatter.PrintResource(nextPrefix(), "ScaledObject", so.Metadata.Name)
+		rp.formatter.Indent()
+		rp.showScaledObject(so, targets, hpaNames)
+		rp.formatter.Outdent()
+	}
+
+	for _, sj := range external.scaledJobs {
+		rp.formatter.PrintResource(nextPrefix(), "ScaledJob", sj.Metadata.Name)
+		rp.formatter.Indent()
+		rp.showScaledJob(sj)
+		rp.formatter.Outdent()
+	}
+
+	for _, vpa := range external.vpas {
+		rp.formatter.PrintResource(nextPrefix(), "VPA", vpa.Metadata.Name)
+		rp.formatter.Indent()
+		rp.showVPA(vpa, targets, lintFindings)
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
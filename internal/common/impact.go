@@ -0,0 +1,250 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reloadBehavior describes how a usage picks up a change to the object and
+// whether the consuming pods must restart for it
+func (u configUsage) reloadBehavior() (string, bool) {
+	switch u.source {
+	case usageEnv, usageEnvFrom:
+		return "restart required, environment is read at container start", true
+	case usageImagePull:
+		return "no restart, used for the next image pull", false
+	case usageCSISecret:
+		return "no restart, used when the volume is mounted", false
+	}
+	switch {
+	case u.container == "":
+		return "no restart, not mounted", false
+	case u.subPath != "":
+		return "restart required, subPath mounts are not updated", true
+	case u.source == usageSecretStore:
+		return "hot reload on rotation by the secrets-store driver", false
+	}
+	return "hot reload, mounted files update in place", false
+}
+
+// impactedWorkload is a workload consuming the changed object
+type impactedWorkload struct {
+	template podTemplate
+	usages   []configUsage
+	restart  bool
+}
+
+// impactTemplates returns the pod templates a config change can reach: those
+// of Deployments, StatefulSets, DaemonSets and CronJobs, and for the running
+// pods of any other owner (Jobs, bare ReplicaSets, custom controllers) or of no
+// owner, the spec of one of its pods
+func (rp *ResourceProcessor) impactTemplates(namespace string) ([]podTemplate, error) {
+	var templates []podTemplate
+
+	deployments, err := rp.clientset.AppsV1().Deployments(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting deployments: %v", err)
+	}
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		templates = append(templates, podTemplate{
+			kind: "Deployment", name: deploy.Name, labels: deploy.Spec.Template.Labels, spec: &deploy.Spec.Template.Spec,
+		})
+	}
+
+	statefulSets, err := rp.clientset.AppsV1().StatefulSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting statefulsets: %v", err)
+	}
+	for i := range statefulSets.Items {
+		sts := &statefulSets.Items[i]
+		templates = append(templates, podTemplate{
+			kind: "StatefulSet", name: sts.Name, labels: sts.Spec.Template.Labels, spec: &sts.Spec.Template.Spec,
+		})
+	}
+
+	daemonSets, err := rp.clientset.AppsV1().DaemonSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting daemonsets: %v", err)
+	}
+	for i := range daemonSets.Items {
+		ds := &daemonSets.Items[i]
+		templates = append(templates, podTemplate{
+			kind: "DaemonSet", name: ds.Name, labels: ds.Spec.Template.Labels, spec: &ds.Spec.Template.Spec,
+		})
+	}
+
+	cronJobs, err := rp.clientset.BatchV1().CronJobs(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting cronjobs: %v", err)
+	}
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		template := &cronJob.Spec.JobTemplate.Spec.Template
+		templates = append(templates, podTemplate{
+			kind: "CronJob", name: cronJob.Name, labels: template.Labels, spec: &template.Spec,
+		})
+	}
+
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %v", err)
+	}
+	known := make(map[workloadRef]bool)
+	for _, template := range templates {
+		known[workloadRef{Kind: template.kind, Name: template.name}] = true
+	}
+	var running []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			running = append(running, pod)
+		}
+	}
+	for _, group := range rp.newOwnerResolver(namespace).groupPodsByOwner(running) {
+		if known[group.owner] {
+			continue
+		}
+		pod := group.pods[0]
+		templates = append(templates, podTemplate{
+			kind: group.owner.Kind, name: group.owner.Name, labels: pod.Labels, spec: &pod.Spec,
+		})
+	}
+
+	return templates, nil
+}
+
+// restartCommand returns the command that rolls a workload's pods, or a
+// comment for a pod without a controller. Other owners have no generic restart
+// command and report false
+func (w *impactedWorkload) restartCommand(namespace string) (string, bool) {
+	switch w.template.kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return fmt.Sprintf("kubectl rollout restart %s/%s -n %s",
+			strings.ToLower(w.template.kind), w.template.name, namespace), true
+	case "Pod":
+		return fmt.Sprintf("# Pod/%s has no controller; delete and recreate it", w.template.name), true
+	}
+	return "", false
+}
+
+// ShowRolloutImpact lists the workloads consuming a ConfigMap or Secret and
+// whether each needs a restart to pick up a change: environment variables and
+// subPath mounts are only read at container start, while other volume mounts
+// update in place. CronJobs pick up changes on their next run. When
+// restartCommands is set, the rollout commands for the affected workloads are
+// printed as well.
+func (rp *ResourceProcessor) ShowRolloutImpact(namespace, kind, name string, restartCommands bool) error {
+	rp.formatter.PrintHeader(fmt.Sprintf("Rollout impact of %s %s/%s", kind, namespace, name))
+	rp.formatter.PrintLine()
+
+	var usagesFor func(pod *corev1.Pod) []configUsage
+	var immutable *bool
+	var err error
+	switch kind {
+	case "ConfigMap":
+		var cm *corev1.ConfigMap
+		cm, err = rp.clientset.CoreV1().ConfigMaps(namespace).Get(rp.ctx, name, metav1.GetOptions{})
+		if err == nil {
+			immutable = cm.Immutable
+		}
+		usagesFor = func(pod *corev1.Pod) []configUsage {
+			return configMapUsagesInPod(pod, name)
+		}
+	case "Secret":
+		var secret *corev1.Secret
+		secret, err = rp.clientset.CoreV1().Secrets(namespace).Get(rp.ctx, name, metav1.GetOptions{})
+		if err == nil {
+			immutable = secret.Immutable
+		}
+		sources := rp.loadSecretSources(namespace)
+		rp.warnPartialSources(sources)
+		usagesFor = func(pod *corev1.Pod) []configUsage {
+			return secretUsagesInPod(pod, name, sources)
+		}
+	default:
+		return fmt.Errorf("unsupported kind %q, expected ConfigMap or Secret", kind)
+	}
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error getting %s: %v", strings.ToLower(kind), err)
+		}
+		rp.formatter.PrintWarning("%s not found; showing references only", kind)
+	}
+	if immutable != nil && *immutable {
+		rp.formatter.PrintWarning("%s is immutable; changing it means creating a new object and updating the references", kind)
+	}
+
+	templates, err := rp.impactTemplates(namespace)
+	if err != nil {
+		return err
+	}
+
+	var workloads []*impactedWorkload
+	for _, template := range templates {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: template.name, Namespace: namespace, Labels: template.labels},
+			Spec:       *template.spec,
+		}
+		usages := usagesFor(pod)
+		if len(usages) == 0 {
+			continue
+		}
+		workload := &impactedWorkload{template: template, usages: usages}
+		for _, usage := range usages {
+			if _, restart := usage.reloadBehavior(); restart {
+				workload.restart = true
+			}
+		}
+		workloads = append(workloads, workload)
+	}
+
+	fmt.Println("\n[Consumers]")
+	if len(workloads) == 0 {
+		rp.formatter.PrintStatus("No workload consumes this "+kind, true)
+		return nil
+	}
+
+	var restarts []*impactedWorkload
+	for i, workload := range workloads {
+		isLast := i == len(workloads)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, workload.template.kind, workload.template.name)
+		rp.formatter.Indent()
+		switch {
+		case workload.template.kind == "CronJob":
+			rp.formatter.PrintStatus("Picks up the change on its next run", true)
+		case workload.restart:
+			rp.formatter.PrintStatus("Restart required", false)
+			if _, ok := workload.restartCommand(namespace); !ok {
+				rp.formatter.PrintInfo("", "No rollout command for %s; recreate its pods to pick up the change", workload.template.kind)
+			}
+			restarts = append(restarts, workload)
+		default:
+			rp.formatter.PrintStatus("No restart required", true)
+		}
+		for _, usage := range workload.usages {
+			behavior, _ := usage.reloadBehavior()
+			rp.formatter.PrintInfo("", "%s: %s", usage.consumer(), behavior)
+		}
+		rp.formatter.Outdent()
+	}
+
+	fmt.Printf("\n%d of %d consumer(s) need a restart\n", len(restarts), len(workloads))
+	if restartCommands && len(restarts) > 0 {
+		fmt.Println("\n[Restart Commands]")
+		for _, workload := range restarts {
+			if command, ok := workload.restartCommand(namespace); ok {
+				fmt.Println(command)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// keyConsumers is a data key of a ConfigMap or Secret with the workloads reading it
+type keyConsumers struct {
+	key       string
+	consumers []string
+}
+
+// missingKey is a reference to a key the object does not have
+type missingKey struct {
+	key      string
+	consumer string
+}
+
+// keyMatrix maps the data keys of a ConfigMap or Secret to the containers
+// consuming them
+type keyMatrix struct {
+	keys []keyConsumers
+	// wholeObject lists the consumers reading every key, through envFrom or
+	// volumes without items
+	wholeObject []string
+	missing     []missingKey
+}
+
+// consumer describes where a usage reads the object, e.g. "env DB_HOST in container api"
+func (u configUsage) consumer() string {
+	in := ""
+	if u.container != "" {
+		in = fmt.Sprintf(" in %s %s", u.containerType, u.container)
+	}
+	switch u.source {
+	case usageEnv:
+		return fmt.Sprintf("env %s%s", u.envVar, in)
+	case usageEnvFrom:
+		return "envFrom" + in
+	case usageImagePull:
+		if u.serviceAccount != "" {
+			return "imagePullSecret via ServiceAccount " + u.serviceAccount
+		}
+		return "imagePullSecret"
+	case usageCSISecret:
+		return "CSI volume " + u.volume
+	}
+	if u.container == "" {
+		return fmt.Sprintf("volume %s (not mounted)", u.volume)
+	}
+	mountPath := u.mountPath
+	if u.subPath != "" {
+		mountPath += fmt.Sprintf(" (subPath %s)", u.subPath)
+	}
+	return fmt.Sprintf("volume %s at %s%s", u.volume, mountPath, in)
+}
+
+// buildKeyMatrix joins the data keys of an object with the usages of its
+// consumers, taking one pod per owning workload
+func buildKeyMatrix(keys []string, groups []*podGroup, usages func(*corev1.Pod) []configUsage) *keyMatrix {
+	matrix := &keyMatrix{}
+	byKey := make(map[string]*keyConsumers, len(keys))
+	for _, key := range keys {
+		matrix.keys = append(matrix.keys, keyConsumers{key: key})
+	}
+	for i := range matrix.keys {
+		byKey[matrix.keys[i].key] = &matrix.keys[i]
+	}
+
+	for _, group := range groups {
+		for _, usage := range usages(group.pods[0]) {
+			consumer := fmt.Sprintf("%s (%s)", group.owner, usage.consumer())
+			if usage.keys == nil {
+				matrix.wholeObject = append(matrix.wholeObject, consumer)
+				continue
+			}
+			for _, key := range usage.keys {
+				if entry, ok := byKey[key]; ok {
+					entry.consumers = append(entry.consumers, consumer)
+				} else if !usage.optional {
+					matrix.missing = append(matrix.missing, missingKey{key: key, consumer: consumer})
+				}
+			}
+		}
+	}
+	return matrix
+}
+
+// showKeyMatrix prints which consumers read each key of a ConfigMap or Secret,
+// flagging keys nothing reads and references to keys that do not exist
+func (rp *ResourceProcessor) showKeyMatrix(namespace string, keys []string, consumers []corev1.Pod, usages func(*corev1.Pod) []configUsage) {
+	groups := rp.newOwnerResolver(namespace).groupPodsByOwner(consumers)
+	matrix := buildKeyMatrix(keys, groups, usages)
+
+	rp.formatter.PrintInfo("", "Keys:")
+	if len(matrix.wholeObject) > 0 {
+		rp.formatter.PrintInfo("", "  All keys: %s", strings.Join(matrix.wholeObject, ", "))
+	}
+	for _, entry := range matrix.keys {
+		switch {
+		case len(entry.consumers) > 0:
+			rp.formatter.PrintStatus(fmt.Sprintf("  %s: %s", entry.key, strings.Join(entry.consumers, ", ")), true)
+		case len(matrix.wholeObject) > 0:
+			rp.formatter.PrintStatus(fmt.Sprintf("  %s: all-keys consumers only", entry.key), true)
+		default:
+			rp.formatter.PrintStatus(fmt.Sprintf("  %s: not referenced", entry.key), false)
+		}
+	}
+	for _, missing := range matrix.missing {
+		rp.formatter.PrintWarning("Key %s does not exist but is read by %s", missing.key, missing.consumer)
+	}
+}
+
+// configMapKeys returns the data and binary data keys of a ConfigMap
+func configMapKeys(cm *corev1.ConfigMap) map[string]bool {
+	keys := make(map[string]bool, len(cm.Data)+len(cm.BinaryData))
+	for key := range cm.Data {
+		keys[key] = true
+	}
+	for key := range cm.BinaryData {
+		keys[key] = true
+	}
+	return keys
+}
+
+// secretKeys returns the data keys of a Secret
+func secretKeys(secret *corev1.Secret) map[string]bool {
+	keys := make(map[string]bool, len(secret.Data))
+	for key := range secret.Data {
+		keys[key] = true
+	}
+	return keys
+}
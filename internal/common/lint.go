@@ -0,0 +1,1142 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity ranks how serious a lint finding is
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// ParseSeverity converts "info", "warning" or "error" to a Severity
+func ParseSeverity(value string) (Severity, error) {
+	switch strings.ToLower(value) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity '%s'", value)
+}
+
+// Finding is a single problem reported by a lint rule
+type Finding struct {
+	ID       string
+	Severity Severity
+	Resource string
+	Message  string
+}
+
+// LintConfig enables, disables and tunes lint rules by ID
+type LintConfig struct {
+	FailOn string                `json:"failOn,omitempty"`
+	Rules  map[string]RuleConfig `json:"rules,omitempty"`
+}
+
+// RuleConfig overrides the defaults of a single lint rule
+type RuleConfig struct {
+	Enabled  *bool             `json:"enabled,omitempty"`
+	Severity string            `json:"severity,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+// RuleInfo describes a lint rule and its defaults
+type RuleInfo struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+type lintRule struct {
+	id          string
+	severity    Severity
+	description string
+	// reference marks rules that detect dangling references between objects
+	reference bool
+	check     func(lc *lintContext, rule *activeRule)
+}
+
+// activeRule is a lint rule with its configuration applied
+type activeRule struct {
+	id       string
+	severity Severity
+	params   map[string]string
+	findings *[]Finding
+}
+
+func (r *activeRule) report(resource, format string, a ...interface{}) {
+	*r.findings = append(*r.findings, Finding{
+		ID:       r.id,
+		Severity: r.severity,
+		Resource: resource,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (r *activeRule) param(name, fallback string) string {
+	if value, ok := r.params[name]; ok {
+		return value
+	}
+	return fallback
+}
+
+func (r *activeRule) intParam(name string, fallback int) int {
+	value, err := strconv.Atoi(r.param(name, strconv.Itoa(fallback)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// lintRules is the registry of all lint rules, in reporting order
+var lintRules = []lintRule{
+	{
+		id:          "container-no-limits",
+		severity:    SeverityWarning,
+		description: "Containers without CPU or memory limits (param resources: cpu,memory)",
+		check:       checkContainerLimits,
+	},
+	{
+		id:          "single-replica-behind-ingress",
+		severity:    SeverityWarning,
+		description: "Workloads exposed through an Ingress with fewer than minReplicas replicas (param minReplicas: 2)",
+		check:       checkSingleReplicaBehindIngress,
+	},
+	{
+		id:          "image-latest",
+		severity:    SeverityWarning,
+		description: "Container images using the :latest tag or no tag at all",
+		check:       checkImageLatest,
+	},
+	{
+		id:          "hpa-target-no-cpu-requests",
+		severity:    SeverityError,
+		description: "HPAs scaling on CPU utilization whose target has containers without CPU requests",
+		check:       checkHPARequests(corev1.ResourceCPU),
+	},
+	{
+		id:          "hpa-target-no-memory-requests",
+		severity:    SeverityError,
+		description: "HPAs scaling on memory utilization whose target has containers without memory requests",
+		check:       checkHPARequests(corev1.ResourceMemory),
+	},
+	{
+		id:          "hpa-min-above-replicas",
+		severity:    SeverityWarning,
+		description: "HPAs whose minReplicas is above the replicas their target runs",
+		check:       checkHPAMinReplicas,
+	},
+	{
+		id:          "hpa-multiple-scalers",
+		severity:    SeverityError,
+		description: "Workloads scaled by more than one HPA",
+		check:       checkHPAMultipleScalers,
+	},
+	{
+		id:          "vpa-hpa-conflict",
+		severity:    SeverityError,
+		description: "VPAs updating the requests of a resource an HPA or ScaledObject of the same workload scales on",
+		check:       checkVPAConflicts,
+	},
+	{
+		id:          "pdb-no-pods",
+		severity:    SeverityWarning,
+		description: "PodDisruptionBudgets selecting no running pods",
+		check:       checkPDBSelectsPods,
+	},
+	{
+		id:          "pdb-blocks-evictions",
+		severity:    SeverityError,
+		description: "PodDisruptionBudgets that never allow an eviction of the pods they select",
+		check:       checkPDBBlocksEvictions,
+	},
+	{
+		id:          "workload-no-pdb",
+		severity:    SeverityWarning,
+		description: "Workloads with two or more pods that drains evict and no PodDisruptionBudget fully covers",
+		check:       checkWorkloadPDBCoverage,
+	},
+	{
+		id:          "ingress-missing-service",
+		severity:    SeverityError,
+		description: "Ingress backends pointing at a missing Service or Service port",
+		reference:   true,
+		check:       checkIngressBackends,
+	},
+	{
+		id:          "ingress-missing-tls-secret",
+		severity:    SeverityError,
+		description: "Ingress TLS entries whose Secret does not exist",
+		reference:   true,
+		check:       checkIngressTLSSecrets,
+	},
+	{
+		id:          "service-port-mismatch",
+		severity:    SeverityWarning,
+		description: "Service named target ports that match no container port of the selected pods",
+		reference:   true,
+		check:       checkServiceTargetPorts,
+	},
+	{
+		id:          "missing-configmap-ref",
+		severity:    SeverityError,
+		description: "References to a missing ConfigMap or ConfigMap key",
+		reference:   true,
+		check:       checkConfigMapRefs,
+	},
+	{
+		id:          "missing-secret-ref",
+		severity:    SeverityError,
+		description: "References to a missing Secret or Secret key",
+		reference:   true,
+		check:       checkSecretRefs,
+	},
+	{
+		id:          "hpa-missing-target",
+		severity:    SeverityError,
+		description: "HPAs whose scale target does not exist",
+		reference:   true,
+		check:       checkHPATargets,
+	},
+}
+
+// LintRules lists the available lint rules with their default severities
+func LintRules() []RuleInfo {
+	rules := make([]RuleInfo, 0, len(lintRules))
+	for _, rule := range lintRules {
+		rules = append(rules, RuleInfo{ID: rule.id, Severity: rule.severity, Description: rule.description})
+	}
+	return rules
+}
+
+// LoadLintConfig reads a lint config from a YAML or JSON file
+func LoadLintConfig(path string) (*LintConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lint config: %v", err)
+	}
+
+	var config LintConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing lint config: %v", err)
+	}
+
+	known := make(map[string]bool)
+	for _, rule := range lintRules {
+		known[rule.id] = true
+	}
+	for id, rule := range config.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown lint rule '%s'", id)
+		}
+		if rule.Severity != "" {
+			if _, err := ParseSeverity(rule.Severity); err != nil {
+				return nil, fmt.Errorf("rule '%s': %v", id, err)
+			}
+		}
+	}
+	if config.FailOn != "" && config.FailOn != "none" {
+		if _, err := ParseSeverity(config.FailOn); err != nil {
+			return nil, fmt.Errorf("failOn: %v", err)
+		}
+	}
+	return &config, nil
+}
+
+// podTemplate is the pod spec of a workload, or of a pod without a controller
+type podTemplate struct {
+	kind     string
+	name     string
+	labels   map[string]string
+	spec     *corev1.PodSpec
+	replicas int32
+	ready    int32
+}
+
+func (pt podTemplate) resource() string {
+	return pt.kind + "/" + pt.name
+}
+
+// lintContext holds the namespace objects the lint rules run against
+type lintContext struct {
+	namespace    string
+	pods         []corev1.Pod
+	deployments  []appsv1.Deployment
+	statefulSets []appsv1.StatefulSet
+	daemonSets   []appsv1.DaemonSet
+	replicaSets  []appsv1.ReplicaSet
+	jobs         []batchv1.Job
+	cronJobs     []batchv1.CronJob
+	services     []corev1.Service
+	ingresses    []networkingv1.Ingress
+	hpas         []autoscalingv2.HorizontalPodAutoscaler
+	pdbs         []policyv1.PodDisruptionBudget
+	// scaledObjects and vpas stay empty when their CRDs are not installed
+	scaledObjects []*scaledObject
+	vpas          []*verticalPodAutoscaler
+	configMaps    map[string]*corev1.ConfigMap
+	secrets       map[string]*corev1.Secret
+	templates     []podTemplate
+	// owners resolves the top-level controllers of pods
+	owners *ownerResolver
+}
+
+// Linter runs the configured lint rules over a namespace
+type Linter struct {
+	clientset KubernetesClient
+	ctx       context.Context
+	formatter *Formatter
+	config    *LintConfig
+	// processor discovers and lists the optional autoscaler CRDs
+	processor *ResourceProcessor
+}
+
+// NewLinter creates a Linter; a nil config runs every rule with its defaults
+func NewLinter(clientset KubernetesClient, ctx context.Context, config *LintConfig) *Linter {
+	if config == nil {
+		config = &LintConfig{}
+	}
+	return &Linter{
+		clientset: clientset,
+		ctx:       ctx,
+		formatter: NewFormatter(),
+		config:    config,
+		processor: NewResourceProcessor(clientset, ctx),
+	}
+}
+
+// SetDynamicClient enables the rules on custom resources such as VPAs and
+// KEDA ScaledObjects; without it they see none
+func (l *Linter) SetDynamicClient(client dynamic.Interface) {
+	l.processor.SetDynamicClient(client)
+}
+
+func (l *Linter) loadContext(namespace string) (*lintContext, error) {
+	lc, err := loadWorkloadContext(l.clientset, l.ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := l.clientset.CoreV1().Services(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting services: %v", err)
+	}
+	lc.services = services.Items
+
+	ingresses, err := l.clientset.NetworkingV1().Ingresses(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ingresses: %v", err)
+	}
+	lc.ingresses = ingresses.Items
+
+	hpas, err := l.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting HPAs: %v", err)
+	}
+	lc.hpas = hpas.Items
+
+	pdbs, err := l.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting poddisruptionbudgets: %v", err)
+	}
+	lc.pdbs = pdbs.Items
+
+	external := l.processor.loadAutoscalers(namespace)
+	for _, warning := range external.warnings {
+		l.formatter.PrintWarning("%s", warning)
+	}
+	lc.scaledObjects = external.scaledObjects
+	lc.vpas = external.vpas
+
+	configMaps, err := l.clientset.CoreV1().ConfigMaps(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting configmaps: %v", err)
+	}
+	lc.configMaps = make(map[string]*corev1.ConfigMap)
+	for i := range configMaps.Items {
+		lc.configMaps[configMaps.Items[i].Name] = &configMaps.Items[i]
+	}
+
+	secrets, err := l.clientset.CoreV1().Secrets(namespace).List(l.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets: %v", err)
+	}
+	lc.secrets = make(map[string]*corev1.Secret)
+	for i := range secrets.Items {
+		lc.secrets[secrets.Items[i].Name] = &secrets.Items[i]
+	}
+
+	return lc, nil
+}
+
+// loadWorkloadContext loads the pods and workloads of a namespace with their
+// pod templates, the part of a lint context the workload rules need
+func loadWorkloadContext(clientset KubernetesClient, ctx context.Context, namespace string) (*lintContext, error) {
+	lc := &lintContext{namespace: namespace}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %v", err)
+	}
+	lc.pods = pods.Items
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting deployments: %v", err)
+	}
+	lc.deployments = deployments.Items
+
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting statefulsets: %v", err)
+	}
+	lc.statefulSets = statefulSets.Items
+
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting daemonsets: %v", err)
+	}
+	lc.daemonSets = daemonSets.Items
+
+	replicaSets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting replicasets: %v", err)
+	}
+	lc.replicaSets = replicaSets.Items
+
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting jobs: %v", err)
+	}
+	lc.jobs = jobs.Items
+
+	cronJobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting cronjobs: %v", err)
+	}
+	lc.cronJobs = cronJobs.Items
+
+	// The listed objects seed the resolver so owner chains cost no extra calls
+	resolver := newOwnerResolverFor(clientset, ctx, namespace)
+	for i := range lc.replicaSets {
+		resolver.remember("ReplicaSet", &lc.replicaSets[i])
+	}
+	for i := range lc.jobs {
+		resolver.remember("Job", &lc.jobs[i])
+	}
+	lc.owners = resolver
+	lc.templates = buildPodTemplates(lc, resolver)
+	return lc, nil
+}
+
+// buildPodTemplates collects the pod specs of workloads, CronJobs, standalone
+// ReplicaSets and Jobs, and of pods whose owners have no template of their own:
+// bare pods and pods of controllers the typed clientset does not know
+func buildPodTemplates(lc *lintContext, resolver *ownerResolver) []podTemplate {
+	var templates []podTemplate
+	for i := range lc.deployments {
+		deploy := &lc.deployments[i]
+		replicas := int32(1)
+		if deploy.Spec.Replicas != nil {
+			replicas = *deploy.Spec.Replicas
+		}
+		templates = append(templates, podTemplate{
+			kind: "Deployment", name: deploy.Name, labels: deploy.Spec.Template.Labels,
+			spec: &deploy.Spec.Template.Spec, replicas: replicas, ready: deploy.Status.ReadyReplicas,
+		})
+	}
+	for i := range lc.statefulSets {
+		sts := &lc.statefulSets[i]
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		templates = append(templates, podTemplate{
+			kind: "StatefulSet", name: sts.Name, labels: sts.Spec.Template.Labels,
+			spec: &sts.Spec.Template.Spec, replicas: replicas, ready: sts.Status.ReadyReplicas,
+		})
+	}
+	for i := range lc.daemonSets {
+		ds := &lc.daemonSets[i]
+		templates = append(templates, podTemplate{
+			kind: "DaemonSet", name: ds.Name, labels: ds.Spec.Template.Labels,
+			spec: &ds.Spec.Template.Spec, replicas: ds.Status.DesiredNumberScheduled, ready: ds.Status.NumberReady,
+		})
+	}
+	for i := range lc.cronJobs {
+		cronJob := &lc.cronJobs[i]
+		template := &cronJob.Spec.JobTemplate.Spec.Template
+		templates = append(templates, podTemplate{
+			kind: "CronJob", name: cronJob.Name, labels: template.Labels, spec: &template.Spec, replicas: 1,
+		})
+	}
+	known := func(ref workloadRef) bool {
+		for _, template := range templates {
+			if template.kind == ref.Kind && template.name == ref.Name {
+				return true
+			}
+		}
+		return false
+	}
+	knownController := func(kind, name string) bool {
+		owner := resolver.controllerOf(workloadRef{Kind: kind, Name: name})
+		return owner != nil && known(workloadRef{Kind: owner.Kind, Name: owner.Name})
+	}
+	// ReplicaSets of a Deployment and Jobs of a CronJob are covered by its template
+	for i := range lc.replicaSets {
+		rs := &lc.replicaSets[i]
+		if knownController("ReplicaSet", rs.Name) {
+			continue
+		}
+		replicas := int32(1)
+		if rs.Spec.Replicas != nil {
+			replicas = *rs.Spec.Replicas
+		}
+		templates = append(templates, podTemplate{
+			kind: "ReplicaSet", name: rs.Name, labels: rs.Spec.Template.Labels,
+			spec: &rs.Spec.Template.Spec, replicas: replicas, ready: rs.Status.ReadyReplicas,
+		})
+	}
+	for i := range lc.jobs {
+		job := &lc.jobs[i]
+		if knownController("Job", job.Name) {
+			continue
+		}
+		templates = append(templates, podTemplate{
+			kind: "Job", name: job.Name, labels: job.Spec.Template.Labels, spec: &job.Spec.Template.Spec, replicas: 1,
+		})
+	}
+
+	// Pods with no template anywhere in their owner chain are grouped by
+	// top-level owner and checked through their first pod
+	var uncovered []corev1.Pod
+	for i := range lc.pods {
+		covered := false
+		for _, ref := range resolver.chain(&lc.pods[i]) {
+			if known(ref) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, lc.pods[i])
+		}
+	}
+	for _, group := range resolver.groupPodsByOwner(uncovered) {
+		pod := group.pods[0]
+		ready := int32(0)
+		for _, member := range group.pods {
+			if isPodReady(member) {
+				ready++
+			}
+		}
+		templates = append(templates, podTemplate{
+			kind: group.owner.Kind, name: group.owner.Name, labels: pod.Labels, spec: &pod.Spec,
+			replicas: int32(len(group.pods)), ready: ready,
+		})
+	}
+	return templates
+}
+
+// findTemplate returns the pod template of the named workload
+func (lc *lintContext) findTemplate(kind, name string) *podTemplate {
+	for i := range lc.templates {
+		if lc.templates[i].kind == kind && lc.templates[i].name == name {
+			return &lc.templates[i]
+		}
+	}
+	return nil
+}
+
+// findScaleTarget returns the pod template of a workload an HPA can scale:
+// a Deployment, StatefulSet or ReplicaSet. ReplicaSets are not part of the
+// templates since their Deployment covers them
+func (lc *lintContext) findScaleTarget(kind, name string) *podTemplate {
+	switch kind {
+	case "Deployment", "StatefulSet":
+		return lc.findTemplate(kind, name)
+	case "ReplicaSet":
+		for i := range lc.replicaSets {
+			rs := &lc.replicaSets[i]
+			if rs.Name != name {
+				continue
+			}
+			replicas := int32(1)
+			if rs.Spec.Replicas != nil {
+				replicas = *rs.Spec.Replicas
+			}
+			return &podTemplate{
+				kind: "ReplicaSet", name: rs.Name, labels: rs.Spec.Template.Labels,
+				spec: &rs.Spec.Template.Spec, replicas: replicas, ready: rs.Status.ReadyReplicas,
+			}
+		}
+	}
+	return nil
+}
+
+// findService returns the named Service
+func (lc *lintContext) findService(name string) *corev1.Service {
+	for i := range lc.services {
+		if lc.services[i].Name == name {
+			return &lc.services[i]
+		}
+	}
+	return nil
+}
+
+// runLintRules runs the named rules with their default settings over a context
+func runLintRules(lc *lintContext, ids ...string) []Finding {
+	var findings []Finding
+	for _, rule := range lintRules {
+		for _, id := range ids {
+			if rule.id == id {
+				rule.check(lc, &activeRule{id: rule.id, severity: rule.severity, findings: &findings})
+			}
+		}
+	}
+	return findings
+}
+
+// findingsFor returns the findings reported for one resource
+func findingsFor(findings []Finding, resource string) []Finding {
+	var matched []Finding
+	for _, finding := range findings {
+		if finding.Resource == resource {
+			matched = append(matched, finding)
+		}
+	}
+	return matched
+}
+
+// Lint runs every enabled rule over the namespace and returns the findings
+// ordered by severity
+func (l *Linter) Lint(namespace string) ([]Finding, error) {
+	return l.lint(namespace, func(rule lintRule) bool { return true })
+}
+
+// LintReferences runs only the enabled rules that detect dangling references
+func (l *Linter) LintReferences(namespace string) ([]Finding, error) {
+	return l.lint(namespace, func(rule lintRule) bool { return rule.reference })
+}
+
+func (l *Linter) lint(namespace string, include func(rule lintRule) bool) ([]Finding, error) {
+	lc, err := l.loadContext(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, rule := range lintRules {
+		config := l.config.Rules[rule.id]
+		if !include(rule) || (config.Enabled != nil && !*config.Enabled) {
+			continue
+		}
+		active := &activeRule{id: rule.id, severity: rule.severity, params: config.Params, findings: &findings}
+		if config.Severity != "" {
+			active.severity, _ = ParseSeverity(config.Severity)
+		}
+		rule.check(lc, active)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+	return findings, nil
+}
+
+// ShowFindings prints the findings of a namespace
+func (l *Linter) ShowFindings(namespace string, findings []Finding) {
+	l.formatter.PrintHeader(fmt.Sprintf("Lint results for namespace: %s", namespace))
+	if len(findings) == 0 {
+		l.formatter.PrintStatus("No findings", true)
+		return
+	}
+
+	l.formatter.Indent()
+	printFindings(l.formatter, findings)
+	l.formatter.Outdent()
+}
+
+// printFindings prints one line per finding, colored by severity
+func printFindings(formatter *Formatter, findings []Finding) {
+	for _, finding := range findings {
+		color := ColorCyan
+		switch finding.Severity {
+		case SeverityError:
+			color = ColorRed
+		case SeverityWarning:
+			color = ColorYellow
+		}
+		fmt.Printf("%s%s[%s]%s %s %s: %s\n", formatter.getIndent(), color, finding.Severity, ColorReset,
+			finding.ID, finding.Resource, finding.Message)
+	}
+}
+
+// allContainers returns the init and app containers of a pod spec
+func allContainers(spec *corev1.PodSpec) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(spec.InitContainers)+len(spec.Containers))
+	containers = append(containers, spec.InitContainers...)
+	return append(containers, spec.Containers...)
+}
+
+func checkContainerLimits(lc *lintContext, rule *activeRule) {
+	resources := strings.Split(rule.param("resources", "cpu,memory"), ",")
+	for _, template := range lc.templates {
+		for _, container := range allContainers(template.spec) {
+			var missing []string
+			for _, name := range resources {
+				name = strings.TrimSpace(name)
+				if _, ok := container.Resources.Limits[corev1.ResourceName(name)]; !ok {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				rule.report(template.resource(), "container '%s' has no %s limit",
+					container.Name, strings.Join(missing, "/"))
+			}
+		}
+	}
+}
+
+func checkSingleReplicaBehindIngress(lc *lintContext, rule *activeRule) {
+	minReplicas := int32(rule.intParam("minReplicas", 2))
+	reported := make(map[string]bool)
+
+	for _, ingress := range lc.ingresses {
+		for _, backend := range ingressBackendServices(&ingress) {
+			service := lc.findService(backend.Name)
+			if service == nil || len(service.Spec.Selector) == 0 {
+				continue
+			}
+			selector := labels.SelectorFromSet(service.Spec.Selector)
+			for _, template := range lc.templates {
+				switch template.kind {
+				case "DaemonSet", "CronJob", "Job":
+					continue
+				}
+				if !selector.Matches(labels.Set(template.labels)) {
+					continue
+				}
+				if template.replicas < minReplicas && !reported[template.resource()] {
+					reported[template.resource()] = true
+					rule.report(template.resource(), "%d replica(s) behind Ingress '%s' via Service '%s'",
+						template.replicas, ingress.Name, service.Name)
+				}
+			}
+		}
+	}
+}
+
+// imageUsesLatest reports whether an image reference resolves to the latest tag
+func imageUsesLatest(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image
+	if slash := strings.LastIndex(image, "/"); slash >= 0 {
+		name = image[slash+1:]
+	}
+	colon := strings.LastIndex(name, ":")
+	return colon < 0 || name[colon+1:] == "latest"
+}
+
+func checkImageLatest(lc *lintContext, rule *activeRule) {
+	for _, template := range lc.templates {
+		for _, container := range allContainers(template.spec) {
+			if imageUsesLatest(container.Image) {
+				rule.report(template.resource(), "container '%s' uses image '%s' without a pinned tag",
+					container.Name, container.Image)
+			}
+		}
+	}
+}
+
+// hpaRequestContainers returns the containers of a target spec whose requests
+// for a resource an HPA needs to compute utilization: all app containers for
+// Resource metrics, the named one for ContainerResource metrics
+func hpaRequestContainers(hpa *autoscalingv2.HorizontalPodAutoscaler, name corev1.ResourceName, spec *corev1.PodSpec) []corev1.Container {
+	all := false
+	named := make(map[string]bool)
+	metrics, _ := hpaMetrics(hpa)
+	for _, metric := range metrics {
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == name && metric.Resource.Target.AverageUtilization != nil:
+			all = true
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil &&
+			metric.ContainerResource.Name == name && metric.ContainerResource.Target.AverageUtilization != nil:
+			named[metric.ContainerResource.Container] = true
+		}
+	}
+
+	var containers []corev1.Container
+	for _, container := range spec.Containers {
+		if all || named[container.Name] {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
+// checkHPARequests flags the containers an HPA scaling on a resource's
+// utilization cannot compute it for
+func checkHPARequests(name corev1.ResourceName) func(lc *lintContext, rule *activeRule) {
+	display := string(name)
+	if name == corev1.ResourceCPU {
+		display = "CPU"
+	}
+	return func(lc *lintContext, rule *activeRule) {
+		for i := range lc.hpas {
+			hpa := &lc.hpas[i]
+			target := lc.findScaleTarget(hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+			if target == nil {
+				continue
+			}
+			for _, container := range hpaRequestContainers(hpa, name, target.spec) {
+				if _, ok := container.Resources.Requests[name]; !ok {
+					rule.report("HorizontalPodAutoscaler/"+hpa.Name,
+						"scales on %s but container '%s' of %s has no %s request",
+						display, container.Name, target.resource(), display)
+				}
+			}
+		}
+	}
+}
+
+func checkHPAMinReplicas(lc *lintContext, rule *activeRule) {
+	for i := range lc.hpas {
+		hpa := &lc.hpas[i]
+		target := lc.findScaleTarget(hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+		if target == nil {
+			continue
+		}
+		if minReplicas, _ := hpaMinReplicas(hpa); minReplicas > target.replicas {
+			rule.report("HorizontalPodAutoscaler/"+hpa.Name, "minReplicas %d is above the %d replica(s) %s runs",
+				minReplicas, target.replicas, target.resource())
+		}
+	}
+}
+
+// checkHPAMultipleScalers flags HPAs sharing a scale target; they fight over
+// its replicas
+func checkHPAMultipleScalers(lc *lintContext, rule *activeRule) {
+	scalers := make(map[workloadRef][]string)
+	for _, hpa := range lc.hpas {
+		ref := workloadRef{Kind: hpa.Spec.ScaleTargetRef.Kind, Name: hpa.Spec.ScaleTargetRef.Name}
+		scalers[ref] = append(scalers[ref], hpa.Name)
+	}
+	for _, hpa := range lc.hpas {
+		ref := workloadRef{Kind: hpa.Spec.ScaleTargetRef.Kind, Name: hpa.Spec.ScaleTargetRef.Name}
+		var others []string
+		for _, name := range scalers[ref] {
+			if name != hpa.Name {
+				others = append(others, name)
+			}
+		}
+		if len(others) > 0 {
+			rule.report("HorizontalPodAutoscaler/"+hpa.Name, "%s is also scaled by HPA %s", ref, strings.Join(others, ", "))
+		}
+	}
+}
+
+// ingressBackendServices returns the Service backends of an Ingress, its
+// default backend included
+func ingressBackendServices(ingress *networkingv1.Ingress) []*networkingv1.IngressServiceBackend {
+	var backends []*networkingv1.IngressServiceBackend
+	if ingress.Spec.DefaultBackend != nil && ingress.Spec.DefaultBackend.Service != nil {
+		backends = append(backends, ingress.Spec.DefaultBackend.Service)
+	}
+	for _, ingressRule := range ingress.Spec.Rules {
+		if ingressRule.HTTP == nil {
+			continue
+		}
+		for i := range ingressRule.HTTP.Paths {
+			if backend := ingressRule.HTTP.Paths[i].Backend.Service; backend != nil {
+				backends = append(backends, backend)
+			}
+		}
+	}
+	return backends
+}
+
+func checkIngressBackends(lc *lintContext, rule *activeRule) {
+	for i := range lc.ingresses {
+		ingress := &lc.ingresses[i]
+		for _, backend := range ingressBackendServices(ingress) {
+			service := lc.findService(backend.Name)
+			if service == nil {
+				rule.report("Ingress/"+ingress.Name, "backend Service '%s' does not exist", backend.Name)
+				continue
+			}
+			if backend.Port.Name == "" && backend.Port.Number == 0 {
+				continue
+			}
+			found := false
+			for _, port := range service.Spec.Ports {
+				if (backend.Port.Name != "" && port.Name == backend.Port.Name) ||
+					(backend.Port.Number != 0 && port.Port == backend.Port.Number) {
+					found = true
+				}
+			}
+			if !found {
+				portRef := backend.Port.Name
+				if portRef == "" {
+					portRef = strconv.Itoa(int(backend.Port.Number))
+				}
+				rule.report("Ingress/"+ingress.Name, "backend Service '%s' has no port '%s'", backend.Name, portRef)
+			}
+		}
+	}
+}
+
+func checkIngressTLSSecrets(lc *lintContext, rule *activeRule) {
+	for _, ingress := range lc.ingresses {
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName != "" && lc.secrets[tls.SecretName] == nil {
+				rule.report("Ingress/"+ingress.Name, "TLS Secret '%s' does not exist", tls.SecretName)
+			}
+		}
+	}
+}
+
+// selectedPodSpecs returns the specs of running pods matching a Service selector,
+// falling back to workload templates when no pods exist
+func (lc *lintContext) selectedPodSpecs(selector map[string]string) []*corev1.PodSpec {
+	set := labels.SelectorFromSet(selector)
+	var specs []*corev1.PodSpec
+	for i := range lc.pods {
+		if set.Matches(labels.Set(lc.pods[i].Labels)) {
+			specs = append(specs, &lc.pods[i].Spec)
+		}
+	}
+	if len(specs) > 0 {
+		return specs
+	}
+	for _, template := range lc.templates {
+		if set.Matches(labels.Set(template.labels)) {
+			specs = append(specs, template.spec)
+		}
+	}
+	return specs
+}
+
+func checkServiceTargetPorts(lc *lintContext, rule *activeRule) {
+	for _, service := range lc.services {
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		specs := lc.selectedPodSpecs(service.Spec.Selector)
+		if len(specs) == 0 {
+			continue
+		}
+
+		for _, port := range service.Spec.Ports {
+			// Traffic reaches a numeric targetPort whether or not a container
+			// declares it; only a named one must resolve
+			if port.TargetPort.Type != intstr.String {
+				continue
+			}
+			found := false
+			for _, spec := range specs {
+				if _, ok := findContainerPort(spec, port); ok {
+					found = true
+				}
+			}
+			if !found {
+				protocol := port.Protocol
+				if protocol == "" {
+					protocol = corev1.ProtocolTCP
+				}
+				rule.report("Service/"+service.Name, "target port '%s' of port %d/%s matches no container port of the selected pods",
+					port.TargetPort.String(), port.Port, protocol)
+			}
+		}
+	}
+}
+
+// objectRef is a reference from a pod spec to a ConfigMap or Secret, optionally to one key
+type objectRef struct {
+	name     string
+	key      string
+	optional bool
+	source   string
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// configMapRefs lists every ConfigMap reference in a pod spec
+func configMapRefs(spec *corev1.PodSpec) []objectRef {
+	var refs []objectRef
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap == nil {
+			continue
+		}
+		source := fmt.Sprintf("volume '%s'", volume.Name)
+		refs = append(refs, objectRef{name: volume.ConfigMap.Name, optional: isOptional(volume.ConfigMap.Optional), source: source})
+		for _, item := range volume.ConfigMap.Items {
+			refs = append(refs, objectRef{name: volume.ConfigMap.Name, key: item.Key, optional: isOptional(volume.ConfigMap.Optional), source: source})
+		}
+	}
+	for _, container := range allContainers(spec) {
+		source := fmt.Sprintf("container '%s'", container.Name)
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				refs = append(refs, objectRef{name: envFrom.ConfigMapRef.Name, optional: isOptional(envFrom.ConfigMapRef.Optional), source: source})
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				ref := env.ValueFrom.ConfigMapKeyRef
+				refs = append(refs, objectRef{name: ref.Name, key: ref.Key, optional: isOptional(ref.Optional), source: source})
+			}
+		}
+	}
+	return refs
+}
+
+// secretRefs lists every Secret reference in a pod spec
+func secretRefs(spec *corev1.PodSpec) []objectRef {
+	var refs []objectRef
+	for _, volume := range spec.Volumes {
+		if volume.Secret == nil {
+			continue
+		}
+		source := fmt.Sprintf("volume '%s'", volume.Name)
+		refs = append(refs, objectRef{name: volume.Secret.SecretName, optional: isOptional(volume.Secret.Optional), source: source})
+		for _, item := range volume.Secret.Items {
+			refs = append(refs, objectRef{name: volume.Secret.SecretName, key: item.Key, optional: isOptional(volume.Secret.Optional), source: source})
+		}
+	}
+	for _, container := range allContainers(spec) {
+		source := fmt.Sprintf("container '%s'", container.Name)
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				refs = append(refs, objectRef{name: envFrom.SecretRef.Name, optional: isOptional(envFrom.SecretRef.Optional), source: source})
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				ref := env.ValueFrom.SecretKeyRef
+				refs = append(refs, objectRef{name: ref.Name, key: ref.Key, optional: isOptional(ref.Optional), source: source})
+			}
+		}
+	}
+	for _, pullSecret := range spec.ImagePullSecrets {
+		refs = append(refs, objectRef{name: pullSecret.Name, source: "imagePullSecrets"})
+	}
+	return refs
+}
+
+// reportMissingRefs reports references to objects or keys that do not exist
+func reportMissingRefs(rule *activeRule, template podTemplate, kind string, refs []objectRef, keys func(name string) (map[string]bool, bool)) {
+	reported := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.optional {
+			continue
+		}
+		existing, ok := keys(ref.name)
+		var message string
+		switch {
+		case !ok:
+			message = fmt.Sprintf("%s '%s' referenced by %s does not exist", kind, ref.name, ref.source)
+		case ref.key != "" && !existing[ref.key]:
+			message = fmt.Sprintf("key '%s' of %s '%s' referenced by %s does not exist", ref.key, kind, ref.name, ref.source)
+		default:
+			continue
+		}
+		if !reported[message] {
+			reported[message] = true
+			rule.report(template.resource(), "%s", message)
+		}
+	}
+}
+
+func checkConfigMapRefs(lc *lintContext, rule *activeRule) {
+	keys := func(name string) (map[string]bool, bool) {
+		cm, ok := lc.configMaps[name]
+		if !ok {
+			return nil, false
+		}
+		existing := make(map[string]bool)
+		for key := range cm.Data {
+			existing[key] = true
+		}
+		for key := range cm.BinaryData {
+			existing[key] = true
+		}
+		return existing, true
+	}
+	for _, template := range lc.templates {
+		reportMissingRefs(rule, template, "ConfigMap", configMapRefs(template.spec), keys)
+	}
+}
+
+func checkSecretRefs(lc *lintContext, rule *activeRule) {
+	keys := func(name string) (map[string]bool, bool) {
+		secret, ok := lc.secrets[name]
+		if !ok {
+			return nil, false
+		}
+		existing := make(map[string]bool)
+		for key := range secret.Data {
+			existing[key] = true
+		}
+		return existing, true
+	}
+	for _, template := range lc.templates {
+		reportMissingRefs(rule, template, "Secret", secretRefs(template.spec), keys)
+	}
+}
+
+func checkHPATargets(lc *lintContext, rule *activeRule) {
+	for _, hpa := range lc.hpas {
+		target := hpa.Spec.ScaleTargetRef
+		switch target.Kind {
+		case "Deployment", "StatefulSet", "ReplicaSet":
+		default:
+			// Custom scale targets cannot be resolved from the core API
+			continue
+		}
+		if lc.findScaleTarget(target.Kind, target.Name) == nil {
+			rule.report("HorizontalPodAutoscaler/"+hpa.Name, "scale target %s/%s does not exist", target.Kind, target.Name)
+		}
+	}
+}
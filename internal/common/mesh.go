@@ -0,0 +1,468 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const istioNetworkingGroup = "networking.istio.io"
+
+// istioVersions are the Istio networking API versions in order of preference
+var istioVersions = []string{"v1", "v1beta1", "v1alpha3"}
+
+// The types below are the subset of the Istio networking schema microlens
+// renders, decoded from unstructured objects
+
+type virtualService struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Hosts    []string    `json:"hosts"`
+		Gateways []string    `json:"gateways"`
+		ExportTo []string    `json:"exportTo"`
+		HTTP     []meshRoute `json:"http"`
+		TLS      []meshRoute `json:"tls"`
+		TCP      []meshRoute `json:"tcp"`
+	} `json:"spec"`
+}
+
+type meshRoute struct {
+	Name  string                   `json:"name"`
+	Match []map[string]interface{} `json:"match"`
+	Route []meshDestination        `json:"route"`
+}
+
+type meshDestination struct {
+	Destination struct {
+		Host   string `json:"host"`
+		Subset string `json:"subset"`
+		Port   struct {
+			Number uint32 `json:"number"`
+		} `json:"port"`
+	} `json:"destination"`
+	Weight int32 `json:"weight"`
+}
+
+type destinationRule struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Host     string   `json:"host"`
+		ExportTo []string `json:"exportTo"`
+		Subsets  []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"subsets"`
+	} `json:"spec"`
+}
+
+// meshIndex holds the Istio traffic rules that apply to a namespace. Rules
+// live in any namespace, such as the Istio root namespace, so they are listed
+// in all namespaces when readable.
+type meshIndex struct {
+	namespace        string
+	virtualServices  []*virtualService
+	destinationRules []*destinationRule
+	// fallback is the error listing all namespaces failed with, when only
+	// the namespace itself could be listed
+	fallback error
+}
+
+// loadMeshIndex returns the VirtualServices and DestinationRules of all
+// namespaces, or of the namespace only when they cannot be listed cluster-wide;
+// nil when Istio is not installed
+func (rp *ResourceProcessor) loadMeshIndex(namespace string) (*meshIndex, error) {
+	index, err := rp.loadMeshRules(namespace, "")
+	if err == nil {
+		return index, nil
+	}
+	index, localErr := rp.loadMeshRules(namespace, namespace)
+	if localErr != nil {
+		return nil, localErr
+	}
+	if index != nil {
+		index.fallback = err
+	}
+	return index, nil
+}
+
+// loadMeshRules lists the Istio rules of scope, all namespaces when empty, for
+// the given namespace
+func (rp *ResourceProcessor) loadMeshRules(namespace, scope string) (*meshIndex, error) {
+	vsGVR, vsServed := rp.findResource(istioNetworkingGroup, "virtualservices", istioVersions...)
+	drGVR, drServed := rp.findResource(istioNetworkingGroup, "destinationrules", istioVersions...)
+	if !vsServed && !drServed {
+		return nil, nil
+	}
+
+	index := &meshIndex{namespace: namespace}
+	if vsServed {
+		items, err := rp.listDynamic(vsGVR, scope)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			vs := &virtualService{}
+			if err := decodeUnstructured(&items[i], vs); err != nil {
+				return nil, fmt.Errorf("error decoding VirtualService %s: %v", items[i].GetName(), err)
+			}
+			index.virtualServices = append(index.virtualServices, vs)
+		}
+	}
+	if drServed {
+		items, err := rp.listDynamic(drGVR, scope)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			dr := &destinationRule{}
+			if err := decodeUnstructured(&items[i], dr); err != nil {
+				return nil, fmt.Errorf("error decoding DestinationRule %s: %v", items[i].GetName(), err)
+			}
+			index.destinationRules = append(index.destinationRules, dr)
+		}
+	}
+	return index, nil
+}
+
+// meshServiceName resolves an Istio host to the name of a Service in the given
+// namespace; short names are relative to the namespace of the rule using them
+func meshServiceName(host, ruleNamespace, namespace string) (string, bool) {
+	parts := strings.Split(host, ".")
+	switch {
+	case len(parts) == 1:
+		return parts[0], ruleNamespace == namespace
+	case len(parts) == 2, len(parts) == 3 && parts[2] == "svc",
+		len(parts) > 3 && parts[2] == "svc" && strings.HasPrefix(strings.Join(parts[3:], "."), "cluster"):
+		return parts[0], parts[1] == namespace
+	}
+	return "", false
+}
+
+// exportedTo reports whether an Istio rule of ruleNamespace with the given
+// exportTo is visible in namespace; rules are visible everywhere by default
+func exportedTo(exportTo []string, ruleNamespace, namespace string) bool {
+	if len(exportTo) == 0 {
+		return true
+	}
+	for _, target := range exportTo {
+		switch target {
+		case "*":
+			return true
+		case ".":
+			if namespace == ruleNamespace {
+				return true
+			}
+		default:
+			if target == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subsetLabels returns the labels of a DestinationRule subset for a Service,
+// looking only at DestinationRules visible in the client namespace
+func (mi *meshIndex) subsetLabels(service, subset, client string) (map[string]string, bool) {
+	for _, dr := range mi.destinationRules {
+		if !exportedTo(dr.Spec.ExportTo, dr.Metadata.Namespace, client) {
+			continue
+		}
+		if name, ok := meshServiceName(dr.Spec.Host, dr.Metadata.Namespace, mi.namespace); !ok || name != service {
+			continue
+		}
+		for _, s := range dr.Spec.Subsets {
+			if s.Name == subset {
+				return s.Labels, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// subsetPods returns the pods of a Service carrying the labels of a subset
+func subsetPods(pods []corev1.Pod, subsetLabels map[string]string) []corev1.Pod {
+	selector := labels.SelectorFromSet(subsetLabels)
+	var selected []corev1.Pod
+	for _, pod := range pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			selected = append(selected, pod)
+		}
+	}
+	return selected
+}
+
+func readyCount(pods []corev1.Pod) int {
+	ready := 0
+	for i := range pods {
+		if isPodReady(&pods[i]) {
+			ready++
+		}
+	}
+	return ready
+}
+
+// describeMeshMatch renders an Istio match block such as
+// {uri: {prefix: /api}, headers: {x-canary: {exact: "true"}}}
+func describeMeshMatch(match map[string]interface{}) string {
+	keys := make([]string, 0, len(match))
+	for key := range match {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		switch value := match[key].(type) {
+		case map[string]interface{}:
+			if key == "headers" || key == "queryParams" || key == "sourceLabels" {
+				names := make([]string, 0, len(value))
+				for name := range value {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					parts = append(parts, fmt.Sprintf("%s %s %s", strings.TrimSuffix(key, "s"), name, describeStringMatch(value[name])))
+				}
+			} else {
+				parts = append(parts, fmt.Sprintf("%s %s", key, describeStringMatch(value)))
+			}
+		case []interface{}:
+			var values []string
+			for _, v := range value {
+				values = append(values, fmt.Sprint(v))
+			}
+			parts = append(parts, fmt.Sprintf("%s %s", key, strings.Join(values, ",")))
+		default:
+			parts = append(parts, fmt.Sprintf("%s %v", key, value))
+		}
+	}
+	if len(parts) == 0 {
+		return "all traffic"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeStringMatch renders an Istio StringMatch ({prefix: /api}) as "prefix /api"
+func describeStringMatch(value interface{}) string {
+	if m, ok := value.(map[string]interface{}); ok {
+		for _, kind := range []string{"exact", "prefix", "regex"} {
+			if v, ok := m[kind]; ok {
+				return fmt.Sprintf("%s %v", kind, v)
+			}
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// destinationWeight returns the share of traffic a destination receives; a
+// single destination without weight receives all of it
+func destinationWeight(route meshRoute, destination meshDestination) int32 {
+	if len(route.Route) == 1 && destination.Weight == 0 {
+		return 100
+	}
+	return destination.Weight
+}
+
+// ShowMeshDetails shows Istio VirtualServices and DestinationRules when Istio
+// is installed, with weighted destinations and the pods behind each subset
+func (rp *ResourceProcessor) ShowMeshDetails(namespace string) error {
+	mesh, err := rp.loadMeshIndex(namespace)
+	if err != nil || mesh == nil {
+		return err
+	}
+	fmt.Println("\n[Service Mesh Layer]")
+	if mesh.fallback != nil {
+		rp.formatter.PrintWarning("Could not list Istio resources in all namespaces, showing %s only: %v", namespace, mesh.fallback)
+	}
+
+	// Rules of other namespaces are listed only to resolve subsets and routes
+	var virtualServices []*virtualService
+	for _, vs := range mesh.virtualServices {
+		if vs.Metadata.Namespace == namespace {
+			virtualServices = append(virtualServices, vs)
+		}
+	}
+	var destinationRules []*destinationRule
+	for _, dr := range mesh.destinationRules {
+		if dr.Metadata.Namespace == namespace {
+			destinationRules = append(destinationRules, dr)
+		}
+	}
+
+	services, err := rp.clientset.CoreV1().Services(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting services: %v", err)
+	}
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+	// Services without a selector are kept with no pods so they still resolve
+	servicePods := make(map[string][]corev1.Pod)
+	for _, service := range services.Items {
+		servicePods[service.Name] = nil
+		if len(service.Spec.Selector) > 0 {
+			servicePods[service.Name] = subsetPods(pods.Items, service.Spec.Selector)
+		}
+	}
+
+	total := len(virtualServices) + len(destinationRules)
+	index := 0
+	nextPrefix := func() string {
+		index++
+		if index == total {
+			return "└──"
+		}
+		return "├──"
+	}
+
+	for _, vs := range virtualServices {
+		rp.formatter.PrintResource(nextPrefix(), "VirtualService", vs.Metadata.Name)
+		rp.formatter.Indent()
+
+		rp.formatter.PrintInfo("", "Hosts: %s", strings.Join(vs.Spec.Hosts, ", "))
+		gateways := vs.Spec.Gateways
+		if len(gateways) == 0 {
+			gateways = []string{"mesh"}
+		}
+		rp.formatter.PrintInfo("", "Gateways: %s", strings.Join(gateways, ", "))
+
+		for _, routes := range []struct {
+			protocol string
+			routes   []meshRoute
+		}{{"HTTP", vs.Spec.HTTP}, {"TLS", vs.Spec.TLS}, {"TCP", vs.Spec.TCP}} {
+			for i, route := range routes.routes {
+				var matches []string
+				for _, match := range route.Match {
+					matches = append(matches, describeMeshMatch(match))
+				}
+				if len(matches) == 0 {
+					matches = append(matches, describeMeshMatch(nil))
+				}
+				label := fmt.Sprintf("%s route %d", routes.protocol, i+1)
+				if route.Name != "" {
+					label += fmt.Sprintf(" (%s)", route.Name)
+				}
+				rp.formatter.PrintInfo("", "%s: %s", label, strings.Join(matches, " or "))
+				rp.formatter.Indent()
+				for _, destination := range route.Route {
+					rp.showMeshDestination(mesh, vs, route, destination, servicePods)
+				}
+				rp.formatter.Outdent()
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	for _, dr := range destinationRules {
+		rp.formatter.PrintResource(nextPrefix(), "DestinationRule", dr.Metadata.Name)
+		rp.formatter.Indent()
+
+		rp.formatter.PrintInfo("", "Host: %s", dr.Spec.Host)
+		service, local := meshServiceName(dr.Spec.Host, dr.Metadata.Namespace, namespace)
+		candidates, found := servicePods[service]
+		if local && !found {
+			rp.formatter.PrintWarning("Service %s not found", service)
+		}
+		for _, subset := range dr.Spec.Subsets {
+			var details []string
+			if len(subset.Labels) > 0 {
+				details = append(details, fmt.Sprintf("Labels: %s", labels.Set(subset.Labels).String()))
+			}
+			if found {
+				selected := subsetPods(candidates, subset.Labels)
+				details = append(details, fmt.Sprintf("Pods: %d (%d ready)", len(selected), readyCount(selected)))
+				rp.formatter.PrintRelation("Subset", subset.Name, details...)
+				if len(selected) == 0 {
+					rp.formatter.PrintWarning("Subset %s matches no pods of Service %s", subset.Name, service)
+				}
+			} else {
+				rp.formatter.PrintRelation("Subset", subset.Name, details...)
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+// showMeshDestination prints one weighted route destination
+func (rp *ResourceProcessor) showMeshDestination(mesh *meshIndex, vs *virtualService, route meshRoute,
+	destination meshDestination, servicePods map[string][]corev1.Pod) {
+	target := destination.Destination
+	var details []string
+	if target.Subset != "" {
+		details = append(details, fmt.Sprintf("Subset: %s", target.Subset))
+	}
+	if target.Port.Number > 0 {
+		details = append(details, fmt.Sprintf("Port: %d", target.Port.Number))
+	}
+	details = append(details, fmt.Sprintf("Weight: %d%%", destinationWeight(route, destination)))
+
+	service, local := meshServiceName(target.Host, vs.Metadata.Namespace, mesh.namespace)
+	if !local {
+		rp.formatter.PrintRelation("Host", target.Host, details...)
+		return
+	}
+	rp.formatter.PrintRelation("Service", service, details...)
+
+	if _, found := servicePods[service]; !found {
+		rp.formatter.PrintWarning("Service %s not found", service)
+		return
+	}
+	if target.Subset != "" {
+		if _, ok := mesh.subsetLabels(service, target.Subset, vs.Metadata.Namespace); !ok {
+			rp.formatter.PrintWarning("Subset %s is not defined by any DestinationRule for %s", target.Subset, service)
+		}
+	}
+}
+
+// showMeshRoutes prints the VirtualService destinations targeting a Service,
+// splitting its pods by subset so canary weights are visible. VirtualServices
+// of other namespaces are named with their namespace.
+func (rp *ResourceProcessor) showMeshRoutes(mesh *meshIndex, service *corev1.Service, pods []corev1.Pod) {
+	for _, vs := range mesh.virtualServices {
+		vsName := vs.Metadata.Name
+		if vs.Metadata.Namespace != mesh.namespace {
+			vsName = vs.Metadata.Namespace + "/" + vsName
+		}
+		for _, routes := range [][]meshRoute{vs.Spec.HTTP, vs.Spec.TLS, vs.Spec.TCP} {
+			for _, route := range routes {
+				for _, destination := range route.Route {
+					name, local := meshServiceName(destination.Destination.Host, vs.Metadata.Namespace, mesh.namespace)
+					if !local || name != service.Name {
+						continue
+					}
+					selected := pods
+					target := "all pods"
+					if subset := destination.Destination.Subset; subset != "" {
+						target = fmt.Sprintf("subset %s", subset)
+						subsetLabels, ok := mesh.subsetLabels(service.Name, subset, vs.Metadata.Namespace)
+						if !ok {
+							rp.formatter.PrintStatus(fmt.Sprintf("VirtualService/%s → %s (%d%%): subset not defined",
+								vsName, target, destinationWeight(route, destination)), false)
+							continue
+						}
+						selected = subsetPods(pods, subsetLabels)
+					}
+					rp.formatter.PrintStatus(fmt.Sprintf("VirtualService/%s → %s (%d%%): %d/%d pods ready",
+						vsName, target, destinationWeight(route, destination), readyCount(selected), len(selected)),
+						readyCount(selected) > 0)
+				}
+			}
+		}
+	}
+}
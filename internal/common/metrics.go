@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -42,6 +43,17 @@ func (rm *ResourceMetrics) formatMemory(bytes int64) string {
 	return fmt.Sprintf("%.2f%s", value, sizes[int(i)])
 }
 
+// podRequests returns the effective CPU (millicores) and memory (bytes) requests
+// of a pod, counting init containers, sidecars and the pod overhead
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	return podSpecRequests(&pod.Spec)
+}
+
+// podLimits returns the effective CPU (millicores) and memory (bytes) limits of a pod
+func podLimits(pod *corev1.Pod) (int64, int64) {
+	return podSpecLimits(&pod.Spec)
+}
+
 // ShowNodeMetrics displays metrics for all nodes
 func (rm *ResourceMetrics) ShowNodeMetrics() error {
 	fmt.Println("\n[Node Metrics]")
@@ -81,12 +93,9 @@ func (rm *ResourceMetrics) ShowNodeMetrics() error {
 
 		var totalCPURequests, totalMemoryRequests int64
 		for _, pod := range pods.Items {
-			for _, container := range pod.Spec.Containers {
-				if container.Resources.Requests != nil {
-					totalCPURequests += container.Resources.Requests.Cpu().MilliValue()
-					totalMemoryRequests += container.Resources.Requests.Memory().Value()
-				}
-			}
+			cpu, memory := podRequests(&pod)
+			totalCPURequests += cpu
+			totalMemoryRequests += memory
 		}
 
 		cpuPercentage := float64(totalCPURequests) / float64(allocatable.Cpu().MilliValue()) * 100
@@ -120,16 +129,12 @@ func (rm *ResourceMetrics) ShowResourceUtilization(namespace string) error {
 	var totalLimitCPU, totalLimitMemory int64
 
 	for _, pod := range pods.Items {
-		for _, container := range pod.Spec.Containers {
-			if container.Resources.Requests != nil {
-				totalRequestCPU += container.Resources.Requests.Cpu().MilliValue()
-				totalRequestMemory += container.Resources.Requests.Memory().Value()
-			}
-			if container.Resources.Limits != nil {
-				totalLimitCPU += container.Resources.Limits.Cpu().MilliValue()
-				totalLimitMemory += container.Resources.Limits.Memory().Value()
-			}
-		}
+		cpu, memory := podRequests(&pod)
+		totalRequestCPU += cpu
+		totalRequestMemory += memory
+		cpu, memory = podLimits(&pod)
+		totalLimitCPU += cpu
+		totalLimitMemory += memory
 	}
 
 	rm.formatter.PrintInfo("", "Namespace Summary:")
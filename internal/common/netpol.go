@@ -0,0 +1,460 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultIngressControllerNamespace is used when ProcessorOptions.IngressControllerNamespace is unset
+const defaultIngressControllerNamespace = "ingress-nginx"
+
+// trafficSource is a set of pods that may send traffic to a pod
+type trafficSource struct {
+	namespace       string
+	namespaceLabels labels.Set
+	// pods holds the candidate source pods; a source is allowed when any of
+	// them is. When empty the source pods are unknown and peers selecting
+	// pods or IPs cannot be evaluated.
+	pods []sourcePod
+}
+
+// sourcePod is the part of a source pod NetworkPolicy peers match on
+type sourcePod struct {
+	labels labels.Set
+	ips    []string
+}
+
+// reachability is the outcome of evaluating NetworkPolicies for a source
+type reachability int
+
+const (
+	reachBlocked reachability = iota
+	reachUnknown
+	reachAllowed
+)
+
+// policyIndex holds the NetworkPolicies of a namespace
+type policyIndex struct {
+	namespace string
+	policies  []networkingv1.NetworkPolicy
+}
+
+func (rp *ResourceProcessor) loadPolicyIndex(namespace string) (*policyIndex, error) {
+	policies, err := rp.clientset.NetworkingV1().NetworkPolicies(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting networkpolicies: %v", err)
+	}
+	return &policyIndex{namespace: namespace, policies: policies.Items}, nil
+}
+
+// selectorMatches evaluates a label selector, treating invalid selectors as matching nothing
+func selectorMatches(selector *metav1.LabelSelector, set labels.Set) bool {
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return parsed.Matches(set)
+}
+
+// hasPolicyType reports whether a policy restricts the given direction. Policies
+// without policyTypes always restrict ingress, and egress only when they have egress rules.
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress ||
+			(policyType == networkingv1.PolicyTypeEgress && len(policy.Spec.Egress) > 0)
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressPoliciesFor returns the policies restricting ingress to a pod
+func (pi *policyIndex) ingressPoliciesFor(pod *corev1.Pod) []*networkingv1.NetworkPolicy {
+	var selected []*networkingv1.NetworkPolicy
+	for i := range pi.policies {
+		policy := &pi.policies[i]
+		if hasPolicyType(policy, networkingv1.PolicyTypeIngress) &&
+			selectorMatches(&policy.Spec.PodSelector, labels.Set(pod.Labels)) {
+			selected = append(selected, policy)
+		}
+	}
+	return selected
+}
+
+// peerAllows reports whether a policy peer admits any pod of the source, or
+// reachUnknown when that depends on source pods or IPs that are not known
+func (pi *policyIndex) peerAllows(peer networkingv1.NetworkPolicyPeer, source trafficSource) reachability {
+	if peer.IPBlock != nil {
+		return ipBlockAllows(peer.IPBlock, source)
+	}
+	if peer.NamespaceSelector == nil {
+		if source.namespace != pi.namespace {
+			return reachBlocked
+		}
+	} else if !selectorMatches(peer.NamespaceSelector, source.namespaceLabels) {
+		return reachBlocked
+	}
+	if peer.PodSelector == nil {
+		return reachAllowed
+	}
+	if len(source.pods) == 0 {
+		return reachUnknown
+	}
+	for _, pod := range source.pods {
+		if selectorMatches(peer.PodSelector, pod.labels) {
+			return reachAllowed
+		}
+	}
+	return reachBlocked
+}
+
+// ipBlockAllows matches an ipBlock peer against the IPs of the source pods,
+// honouring its exceptions
+func ipBlockAllows(block *networkingv1.IPBlock, source trafficSource) reachability {
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil {
+		return reachBlocked
+	}
+	result := reachUnknown
+	for _, pod := range source.pods {
+		for _, value := range pod.ips {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				continue
+			}
+			result = reachBlocked
+			if !cidr.Contains(ip) {
+				continue
+			}
+			excepted := false
+			for _, except := range block.Except {
+				if _, excluded, err := net.ParseCIDR(except); err == nil && excluded.Contains(ip) {
+					excepted = true
+				}
+			}
+			if !excepted {
+				return reachAllowed
+			}
+		}
+	}
+	return result
+}
+
+// portAllowed reports whether a rule's ports admit the given pod port
+func portAllowed(ports []networkingv1.NetworkPolicyPort, pod *corev1.Pod, port int32, protocol corev1.Protocol) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, rulePort := range ports {
+		ruleProtocol := corev1.ProtocolTCP
+		if rulePort.Protocol != nil {
+			ruleProtocol = *rulePort.Protocol
+		}
+		if ruleProtocol != protocol {
+			continue
+		}
+		if rulePort.Port == nil {
+			return true
+		}
+		if rulePort.Port.Type == intstr.String {
+			if containerPort, ok := findContainerPort(&pod.Spec, corev1.ServicePort{
+				Protocol: protocol, TargetPort: *rulePort.Port,
+			}); ok && containerPort.ContainerPort == port {
+				return true
+			}
+			continue
+		}
+		end := rulePort.Port.IntVal
+		if rulePort.EndPort != nil {
+			end = *rulePort.EndPort
+		}
+		if port >= rulePort.Port.IntVal && port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressAllowed reports whether traffic from the source may reach a pod port,
+// along with the names of the policies restricting that pod. The most
+// permissive peer wins: any allowing peer allows, otherwise any unknown one
+// leaves the outcome unknown.
+func (pi *policyIndex) ingressAllowed(pod *corev1.Pod, source trafficSource, port int32, protocol corev1.Protocol) (reachability, []string) {
+	policies := pi.ingressPoliciesFor(pod)
+	if len(policies) == 0 {
+		return reachAllowed, nil
+	}
+
+	var names []string
+	result := reachBlocked
+	for _, policy := range policies {
+		names = append(names, policy.Name)
+		for _, rule := range policy.Spec.Ingress {
+			if !portAllowed(rule.Ports, pod, port, protocol) {
+				continue
+			}
+			if len(rule.From) == 0 {
+				result = reachAllowed
+			}
+			for _, peer := range rule.From {
+				result = max(result, pi.peerAllows(peer, source))
+			}
+		}
+	}
+	return result, names
+}
+
+// describePeer renders a NetworkPolicy peer for display
+func describePeer(peer networkingv1.NetworkPolicyPeer) string {
+	if peer.IPBlock != nil {
+		if len(peer.IPBlock.Except) > 0 {
+			return fmt.Sprintf("ipBlock %s except %v", peer.IPBlock.CIDR, peer.IPBlock.Except)
+		}
+		return fmt.Sprintf("ipBlock %s", peer.IPBlock.CIDR)
+	}
+
+	pods := "all pods"
+	if peer.PodSelector != nil && (len(peer.PodSelector.MatchLabels) > 0 || len(peer.PodSelector.MatchExpressions) > 0) {
+		pods = fmt.Sprintf("pods {%s}", metav1.FormatLabelSelector(peer.PodSelector))
+	}
+	switch {
+	case peer.NamespaceSelector == nil:
+		return pods + " in this namespace"
+	case len(peer.NamespaceSelector.MatchLabels) == 0 && len(peer.NamespaceSelector.MatchExpressions) == 0:
+		return pods + " in all namespaces"
+	}
+	return fmt.Sprintf("%s in namespaces {%s}", pods, metav1.FormatLabelSelector(peer.NamespaceSelector))
+}
+
+// describePorts renders NetworkPolicy ports for display
+func describePorts(ports []networkingv1.NetworkPolicyPort) string {
+	if len(ports) == 0 {
+		return "all ports"
+	}
+	var parts []string
+	for _, port := range ports {
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		switch {
+		case port.Port == nil:
+			parts = append(parts, fmt.Sprintf("all/%s", protocol))
+		case port.EndPort != nil:
+			parts = append(parts, fmt.Sprintf("%s-%d/%s", port.Port.String(), *port.EndPort, protocol))
+		default:
+			parts = append(parts, fmt.Sprintf("%s/%s", port.Port.String(), protocol))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describePeers(peers []networkingv1.NetworkPolicyPeer) string {
+	if len(peers) == 0 {
+		return "anywhere"
+	}
+	var parts []string
+	for _, peer := range peers {
+		parts = append(parts, describePeer(peer))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (rp *ResourceProcessor) ShowNetworkPolicyDetails(namespace string) error {
+	fmt.Println("\n[NetworkPolicy Layer]")
+	index, err := rp.loadPolicyIndex(namespace)
+	if err != nil {
+		return err
+	}
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+	resolver := rp.newOwnerResolver(namespace)
+
+	for i, policy := range index.policies {
+		isLast := i == len(index.policies)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "NetworkPolicy", policy.Name)
+		rp.formatter.Indent()
+
+		var types []string
+		for _, t := range []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress} {
+			if hasPolicyType(&policy, t) {
+				types = append(types, string(t))
+			}
+		}
+		rp.formatter.PrintInfo("", "Policy Types: %s", strings.Join(types, ", "))
+		rp.formatter.PrintInfo("", "Pod Selector: {%s}", metav1.FormatLabelSelector(&policy.Spec.PodSelector))
+
+		var selected []corev1.Pod
+		for _, pod := range pods.Items {
+			if selectorMatches(&policy.Spec.PodSelector, labels.Set(pod.Labels)) {
+				selected = append(selected, pod)
+			}
+		}
+		if len(selected) == 0 {
+			rp.formatter.PrintWarning("Selects no pods")
+		} else {
+			rp.formatter.PrintInfo("", "Selects:")
+			for _, group := range resolver.groupPodsByOwner(selected) {
+				rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name, fmt.Sprintf("Pods: %d", len(group.pods)))
+			}
+		}
+
+		if hasPolicyType(&policy, networkingv1.PolicyTypeIngress) {
+			if len(policy.Spec.Ingress) == 0 {
+				rp.formatter.PrintStatus("Ingress: deny all", false)
+			}
+			for _, rule := range policy.Spec.Ingress {
+				rp.formatter.PrintInfo("", "Ingress: allow from %s on %s", describePeers(rule.From), describePorts(rule.Ports))
+			}
+		}
+		if hasPolicyType(&policy, networkingv1.PolicyTypeEgress) {
+			if len(policy.Spec.Egress) == 0 {
+				rp.formatter.PrintStatus("Egress: deny all", false)
+			}
+			for _, rule := range policy.Spec.Egress {
+				rp.formatter.PrintInfo("", "Egress: allow to %s on %s", describePeers(rule.To), describePorts(rule.Ports))
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	// Pods that no ingress policy selects accept traffic from anywhere
+	var unrestricted []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed &&
+			len(index.ingressPoliciesFor(&pod)) == 0 {
+			unrestricted = append(unrestricted, pod)
+		}
+	}
+	if len(unrestricted) > 0 {
+		rp.formatter.PrintWarning("Unrestricted ingress (no NetworkPolicy selects these pods):")
+		rp.formatter.Indent()
+		for _, group := range resolver.groupPodsByOwner(unrestricted) {
+			rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name, fmt.Sprintf("Pods: %d", len(group.pods)))
+		}
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+// ingressControllerSource describes the Ingress controller pods as a traffic source.
+// When its pods cannot be listed, peers selecting pods or IPs leave the
+// reachability unknown.
+func (rp *ResourceProcessor) ingressControllerSource() trafficSource {
+	namespace := rp.options.IngressControllerNamespace
+	if namespace == "" {
+		namespace = defaultIngressControllerNamespace
+	}
+
+	source := trafficSource{
+		namespace:       namespace,
+		namespaceLabels: labels.Set{"kubernetes.io/metadata.name": namespace},
+	}
+	if ns, err := rp.clientset.CoreV1().Namespaces().Get(rp.ctx, namespace, metav1.GetOptions{}); err == nil {
+		for key, value := range ns.Labels {
+			source.namespaceLabels[key] = value
+		}
+	}
+	if pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for _, pod := range pods.Items {
+			candidate := sourcePod{labels: labels.Set(pod.Labels)}
+			for _, podIP := range pod.Status.PodIPs {
+				candidate.ips = append(candidate.ips, podIP.IP)
+			}
+			if len(candidate.ips) == 0 && pod.Status.PodIP != "" {
+				candidate.ips = append(candidate.ips, pod.Status.PodIP)
+			}
+			source.pods = append(source.pods, candidate)
+		}
+	}
+	return source
+}
+
+// showIngressReachability annotates a Service's pods with whether the Ingress
+// controller may reach their target ports under the namespace's NetworkPolicies
+func (rp *ResourceProcessor) showIngressReachability(index *policyIndex, source trafficSource, service *corev1.Service, pods []corev1.Pod) {
+	if index == nil || len(index.policies) == 0 {
+		return
+	}
+
+	for _, port := range service.Spec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		allowed, unknown := 0, 0
+		blockedBy := make(map[string]bool)
+		for i := range pods {
+			pod := &pods[i]
+			target, ok := containerPortNumber(pod, port)
+			if !ok {
+				continue
+			}
+			result, policies := index.ingressAllowed(pod, source, target, protocol)
+			switch result {
+			case reachAllowed:
+				allowed++
+				continue
+			case reachUnknown:
+				unknown++
+				continue
+			}
+			for _, name := range policies {
+				blockedBy[name] = true
+			}
+		}
+
+		status := fmt.Sprintf("Ingress controller (%s) → port %d: %d/%d pods reachable",
+			source.namespace, port.Port, allowed, len(pods))
+		if len(blockedBy) > 0 {
+			var names []string
+			for name := range blockedBy {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			status += fmt.Sprintf(", blocked by NetworkPolicy %s", strings.Join(names, ", "))
+		}
+		if unknown > 0 {
+			status += fmt.Sprintf(", %d unknown (controller pods or their IPs are not visible)", unknown)
+			if len(blockedBy) == 0 {
+				rp.formatter.PrintWarning("%s", status)
+				continue
+			}
+		}
+		rp.formatter.PrintStatus(status, allowed == len(pods))
+	}
+}
+
+// containerPortNumber resolves a Service port's targetPort to a port number on
+// the pod. A named targetPort must match a container port of the same protocol,
+// sidecar init containers included
+func containerPortNumber(pod *corev1.Pod, port corev1.ServicePort) (int32, bool) {
+	if port.TargetPort.Type == intstr.String {
+		containerPort, ok := findContainerPort(&pod.Spec, port)
+		return containerPort.ContainerPort, ok
+	}
+	if port.TargetPort.IntVal == 0 {
+		return port.Port, true
+	}
+	return port.TargetPort.IntVal, true
+}
@@ -0,0 +1,253 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// leaderElectionAnnotation marks ConfigMaps used as leader election locks
+const leaderElectionAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+// OrphanedObject is a ConfigMap or Secret that nothing in its namespace references
+type OrphanedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	CreatedAt time.Time
+	Size      int
+}
+
+// Age returns how long ago the object was created
+func (o OrphanedObject) Age() time.Duration {
+	return time.Since(o.CreatedAt)
+}
+
+// formatAge renders a duration the way kubectl prints ages
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// workloadPods returns the namespace's pods plus a pod per workload and Job
+// template, so objects used only by scaled-down, idle or finished workloads are
+// not reported as orphans
+func (rp *ResourceProcessor) workloadPods(namespace string) ([]corev1.Pod, error) {
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %v", err)
+	}
+	result := pods.Items
+
+	addTemplate := func(kind, name string, template corev1.PodTemplateSpec) {
+		result = append(result, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: kind + "/" + name, Namespace: namespace, Labels: template.Labels},
+			Spec:       template.Spec,
+		})
+	}
+
+	deployments, err := rp.clientset.AppsV1().Deployments(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting deployments: %v", err)
+	}
+	for _, deploy := range deployments.Items {
+		addTemplate("Deployment", deploy.Name, deploy.Spec.Template)
+	}
+
+	statefulSets, err := rp.clientset.AppsV1().StatefulSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting statefulsets: %v", err)
+	}
+	for _, sts := range statefulSets.Items {
+		addTemplate("StatefulSet", sts.Name, sts.Spec.Template)
+	}
+
+	daemonSets, err := rp.clientset.AppsV1().DaemonSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets.Items {
+		addTemplate("DaemonSet", ds.Name, ds.Spec.Template)
+	}
+
+	cronJobs, err := rp.clientset.BatchV1().CronJobs(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting cronjobs: %v", err)
+	}
+	for _, cronJob := range cronJobs.Items {
+		addTemplate("CronJob", cronJob.Name, cronJob.Spec.JobTemplate.Spec.Template)
+	}
+
+	// Jobs keep their template after their pods are cleaned up
+	jobs, err := rp.clientset.BatchV1().Jobs(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting jobs: %v", err)
+	}
+	for _, job := range jobs.Items {
+		addTemplate("Job", job.Name, job.Spec.Template)
+	}
+
+	return result, nil
+}
+
+// FindOrphanedConfigObjects lists the ConfigMaps and Secrets of a namespace that no
+// pod, workload, Ingress TLS entry, ServiceAccount, imagePullSecret or
+// SecretProviderClass references.
+// Objects with owner references, Helm release records, leader election locks
+// and the cluster CA bundle are managed by controllers and never reported.
+func (rp *ResourceProcessor) FindOrphanedConfigObjects(namespace string) ([]OrphanedObject, error) {
+	pods, err := rp.workloadPods(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// An unreadable source could hide the only reference to a Secret
+	sources := rp.loadSecretSources(namespace)
+	if sources.partial {
+		return nil, sources.err
+	}
+
+	var orphans []OrphanedObject
+
+	configMaps, err := rp.clientset.CoreV1().ConfigMaps(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting configmaps: %v", err)
+	}
+	for _, cm := range configMaps.Items {
+		if cm.Name == "kube-root-ca.crt" || len(cm.OwnerReferences) > 0 {
+			continue
+		}
+		if _, ok := cm.Annotations[leaderElectionAnnotation]; ok {
+			continue
+		}
+		used := false
+		for i := range pods {
+			if len(rp.getConfigMapUsageInPod(&pods[i], cm.Name)) > 0 {
+				used = true
+				break
+			}
+		}
+		if used {
+			continue
+		}
+
+		size := 0
+		for _, value := range cm.Data {
+			size += len(value)
+		}
+		for _, value := range cm.BinaryData {
+			size += len(value)
+		}
+		orphans = append(orphans, OrphanedObject{
+			Kind: "ConfigMap", Namespace: namespace, Name: cm.Name,
+			CreatedAt: cm.CreationTimestamp.Time, Size: size,
+		})
+	}
+
+	secrets, err := rp.clientset.CoreV1().Secrets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets: %v", err)
+	}
+	for _, secret := range secrets.Items {
+		if len(secret.OwnerReferences) > 0 || secret.Type == "helm.sh/release.v1" ||
+			len(sources.referrers(&secret)) > 0 {
+			continue
+		}
+		used := false
+		for i := range pods {
+			if len(rp.getSecretUsageInPod(&pods[i], secret.Name, sources)) > 0 {
+				used = true
+				break
+			}
+		}
+		if used {
+			continue
+		}
+
+		size := 0
+		for _, value := range secret.Data {
+			size += len(value)
+		}
+		orphans = append(orphans, OrphanedObject{
+			Kind: "Secret", Namespace: namespace, Name: secret.Name,
+			CreatedAt: secret.CreationTimestamp.Time, Size: size,
+		})
+	}
+
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return orphans[i].CreatedAt.Before(orphans[j].CreatedAt)
+	})
+	return orphans, nil
+}
+
+// ShowOrphanedConfigObjects prints the orphaned ConfigMaps and Secrets of a namespace
+func (rp *ResourceProcessor) ShowOrphanedConfigObjects(namespace string) ([]OrphanedObject, error) {
+	fmt.Printf("\n[Orphaned Config Objects: %s]\n", namespace)
+
+	orphans, err := rp.FindOrphanedConfigObjects(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orphans) == 0 {
+		rp.formatter.PrintStatus("No orphaned ConfigMaps or Secrets", true)
+		return nil, nil
+	}
+
+	for i, orphan := range orphans {
+		isLast := i == len(orphans)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, orphan.Kind, orphan.Name)
+		rp.formatter.Indent()
+		rp.formatter.PrintInfo("", "Age: %s", formatAge(orphan.Age()))
+		rp.formatter.PrintInfo("", "Size: %s", rp.metrics.formatMemory(int64(orphan.Size)))
+		rp.formatter.Outdent()
+	}
+
+	return orphans, nil
+}
+
+// WriteOrphanManifest writes a v1 List of the given objects, suitable for
+// `kubectl delete -f`, to path
+func WriteOrphanManifest(path string, orphans []OrphanedObject) error {
+	items := make([]map[string]interface{}, 0, len(orphans))
+	for _, orphan := range orphans {
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       orphan.Kind,
+			"metadata": map[string]interface{}{
+				"name":      orphan.Name,
+				"namespace": orphan.Namespace,
+			},
+		})
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding orphan manifest: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing orphan manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// workloadRef identifies an object in the ownership chain of a pod
+type workloadRef struct {
+	Kind string
+	Name string
+}
+
+func (w workloadRef) String() string {
+	return w.Kind + "/" + w.Name
+}
+
+// ownerResolver walks ownerReferences from a pod up to its top-level controller,
+// caching each lookup so pods sharing a ReplicaSet or Job cost one API call
+type ownerResolver struct {
+	clientset KubernetesClient
+	ctx       context.Context
+	namespace string
+	cache     map[workloadRef]*metav1.OwnerReference
+}
+
+func (rp *ResourceProcessor) newOwnerResolver(namespace string) *ownerResolver {
+	return newOwnerResolverFor(rp.clientset, rp.ctx, namespace)
+}
+
+// newOwnerResolverFor creates an ownerResolver for callers without a
+// ResourceProcessor, such as the Linter
+func newOwnerResolverFor(clientset KubernetesClient, ctx context.Context, namespace string) *ownerResolver {
+	return &ownerResolver{
+		clientset: clientset,
+		ctx:       ctx,
+		namespace: namespace,
+		cache:     make(map[workloadRef]*metav1.OwnerReference),
+	}
+}
+
+// remember caches the controller of an object the caller already listed
+func (or *ownerResolver) remember(kind string, object metav1.Object) {
+	or.cache[workloadRef{Kind: kind, Name: object.GetName()}] = metav1.GetControllerOf(object)
+}
+
+// controllerOf returns the controller of the given object, or nil when it has
+// none or cannot be fetched
+func (or *ownerResolver) controllerOf(ref workloadRef) *metav1.OwnerReference {
+	if owner, ok := or.cache[ref]; ok {
+		return owner
+	}
+
+	var object metav1.Object
+	var err error
+	ctx := or.ctx
+	get := metav1.GetOptions{}
+	switch ref.Kind {
+	case "ReplicaSet":
+		object, err = or.clientset.AppsV1().ReplicaSets(or.namespace).Get(ctx, ref.Name, get)
+	case "Deployment":
+		object, err = or.clientset.AppsV1().Deployments(or.namespace).Get(ctx, ref.Name, get)
+	case "StatefulSet":
+		object, err = or.clientset.AppsV1().StatefulSets(or.namespace).Get(ctx, ref.Name, get)
+	case "DaemonSet":
+		object, err = or.clientset.AppsV1().DaemonSets(or.namespace).Get(ctx, ref.Name, get)
+	case "Job":
+		object, err = or.clientset.BatchV1().Jobs(or.namespace).Get(ctx, ref.Name, get)
+	case "CronJob":
+		object, err = or.clientset.BatchV1().CronJobs(or.namespace).Get(ctx, ref.Name, get)
+	default:
+		// Custom controllers cannot be fetched through the typed clientset
+		or.cache[ref] = nil
+		return nil
+	}
+
+	var owner *metav1.OwnerReference
+	if err == nil {
+		owner = metav1.GetControllerOf(object)
+	}
+	or.cache[ref] = owner
+	return owner
+}
+
+// chain returns the ownership chain of a pod, starting with its direct controller
+// and ending with the top-level one
+func (or *ownerResolver) chain(pod *corev1.Pod) []workloadRef {
+	var chain []workloadRef
+	owner := metav1.GetControllerOf(pod)
+	seen := make(map[workloadRef]bool)
+	for owner != nil {
+		ref := workloadRef{Kind: owner.Kind, Name: owner.Name}
+		if seen[ref] {
+			break
+		}
+		seen[ref] = true
+		chain = append(chain, ref)
+		owner = or.controllerOf(ref)
+	}
+	return chain
+}
+
+// podGroup is a set of pods sharing a top-level controller
+type podGroup struct {
+	owner         workloadRef
+	pods          []*corev1.Pod
+	intermediates map[string]int
+}
+
+// groupPodsByOwner groups pods by their top-level controller, preserving the
+// order in which owners are first seen
+func (or *ownerResolver) groupPodsByOwner(pods []corev1.Pod) []*podGroup {
+	var groups []*podGroup
+	byOwner := make(map[workloadRef]*podGroup)
+	for i := range pods {
+		pod := &pods[i]
+		chain := or.chain(pod)
+		owner := workloadRef{Kind: "Pod", Name: pod.Name}
+		if len(chain) > 0 {
+			owner = chain[len(chain)-1]
+		}
+
+		group, ok := byOwner[owner]
+		if !ok {
+			group = &podGroup{owner: owner, intermediates: make(map[string]int)}
+			byOwner[owner] = group
+			groups = append(groups, group)
+		}
+		group.pods = append(group.pods, pod)
+		for _, ref := range chain[:max(len(chain)-1, 0)] {
+			group.intermediates[ref.String()]++
+		}
+	}
+	return groups
+}
+
+// showConnectedPods prints the pods selected by a Service collapsed by their
+// top-level controller, with the ownership chain and a ready count
+func (rp *ResourceProcessor) showConnectedPods(namespace string, pods []corev1.Pod) {
+	resolver := rp.newOwnerResolver(namespace)
+	for _, group := range resolver.groupPodsByOwner(pods) {
+		if group.owner.Kind == "Pod" {
+			pod := group.pods[0]
+			details := []string{
+				fmt.Sprintf("Status: %s", pod.Status.Phase),
+			}
+			if pod.Status.PodIP != "" {
+				details = append(details, fmt.Sprintf("IP: %s", pod.Status.PodIP))
+			}
+			if pod.Spec.NodeName != "" {
+				details = append(details, fmt.Sprintf("Node: %s", pod.Spec.NodeName))
+			}
+			rp.formatter.PrintRelation("Pod", pod.Name, details...)
+			continue
+		}
+
+		ready := 0
+		var notReady []string
+		for _, pod := range group.pods {
+			if isPodReady(pod) {
+				ready++
+			} else {
+				notReady = append(notReady, fmt.Sprintf("%s (%s)", pod.Name, pod.Status.Phase))
+			}
+		}
+
+		var via []string
+		for ref, count := range group.intermediates {
+			via = append(via, fmt.Sprintf("%s (%d)", ref, count))
+		}
+		sort.Strings(via)
+
+		chain := fmt.Sprintf("Chain: Pod → %s", group.owner)
+		if len(via) > 0 {
+			chain = fmt.Sprintf("Chain: Pod → %s → %s", strings.Join(via, ", "), group.owner)
+		}
+		details := []string{fmt.Sprintf("Ready: %d/%d pods", ready, len(group.pods)), chain}
+		if len(notReady) > 0 {
+			details = append(details, fmt.Sprintf("Not Ready: %s", strings.Join(notReady, ", ")))
+		}
+		rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name, details...)
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// pdbBlocksEvictions reports whether a PodDisruptionBudget can never allow an
+// eviction of the given number of pods, whatever their health. Percentages are
+// rounded up as the disruption controller does.
+func pdbBlocksEvictions(pdb *policyv1.PodDisruptionBudget, pods int) (string, bool) {
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		allowed, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, pods, true)
+		if err == nil && allowed == 0 {
+			return fmt.Sprintf("maxUnavailable %s allows no disruption", pdb.Spec.MaxUnavailable.String()), true
+		}
+	case pdb.Spec.MinAvailable != nil && pods > 0:
+		desired, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, pods, true)
+		if err == nil && desired >= pods {
+			return fmt.Sprintf("minAvailable %s leaves no disruption for the %d selected pod(s)",
+				pdb.Spec.MinAvailable.String(), pods), true
+		}
+	}
+	return "", false
+}
+
+// needsPDB reports whether pods of a top-level controller are evicted by node
+// drains and so should be covered by a budget: DaemonSet pods are skipped by
+// drains and batch pods run to completion
+func needsPDB(owner workloadRef) bool {
+	switch owner.Kind {
+	case "Pod", "DaemonSet", "Job", "CronJob":
+		return false
+	}
+	return true
+}
+
+// pdbLintRules are the lint rules checking drain safety, shown in the
+// PodDisruptionBudget layer
+var pdbLintRules = []string{"pdb-no-pods", "pdb-blocks-evictions", "workload-no-pdb"}
+
+// runningPods returns the pods that are not finished; finished pods hold no
+// budget and cannot be evicted
+func runningPods(pods []corev1.Pod) []corev1.Pod {
+	var running []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			running = append(running, pod)
+		}
+	}
+	return running
+}
+
+// pdbSelectedPods returns the pods a PodDisruptionBudget selects; a nil
+// selector selects no pods and an empty one every pod
+func pdbSelectedPods(pdb *policyv1.PodDisruptionBudget, pods []corev1.Pod) []corev1.Pod {
+	var selected []corev1.Pod
+	for _, pod := range pods {
+		if selectorMatches(pdb.Spec.Selector, labels.Set(pod.Labels)) {
+			selected = append(selected, pod)
+		}
+	}
+	return selected
+}
+
+func checkPDBSelectsPods(lc *lintContext, rule *activeRule) {
+	pods := runningPods(lc.pods)
+	for i := range lc.pdbs {
+		if len(pdbSelectedPods(&lc.pdbs[i], pods)) == 0 {
+			rule.report("PodDisruptionBudget/"+lc.pdbs[i].Name, "selects no pods")
+		}
+	}
+}
+
+func checkPDBBlocksEvictions(lc *lintContext, rule *activeRule) {
+	pods := runningPods(lc.pods)
+	for i := range lc.pdbs {
+		pdb := &lc.pdbs[i]
+		selected := len(pdbSelectedPods(pdb, pods))
+		if selected == 0 {
+			continue
+		}
+		if message, blocks := pdbBlocksEvictions(pdb, selected); blocks {
+			rule.report("PodDisruptionBudget/"+pdb.Name, "%s", message)
+		}
+	}
+}
+
+// checkWorkloadPDBCoverage flags multi-replica workloads evicted by drains
+// with pods no PodDisruptionBudget selects; a workload is covered only when
+// budgets select every one of its pods
+func checkWorkloadPDBCoverage(lc *lintContext, rule *activeRule) {
+	pods := runningPods(lc.pods)
+	covered := make(map[string]bool)
+	for i := range lc.pdbs {
+		for _, pod := range pdbSelectedPods(&lc.pdbs[i], pods) {
+			covered[pod.Name] = true
+		}
+	}
+
+	for _, group := range lc.owners.groupPodsByOwner(pods) {
+		if len(group.pods) < 2 || !needsPDB(group.owner) {
+			continue
+		}
+		missing := 0
+		for _, pod := range group.pods {
+			if !covered[pod.Name] {
+				missing++
+			}
+		}
+		switch {
+		case missing == len(group.pods):
+			rule.report(group.owner.String(), "runs %d pods and no PodDisruptionBudget covers them", len(group.pods))
+		case missing > 0:
+			rule.report(group.owner.String(), "runs %d pods and no PodDisruptionBudget covers %d of them",
+				len(group.pods), missing)
+		}
+	}
+}
+
+// ShowPDBDetails prints the PodDisruptionBudgets of a namespace with the
+// workloads whose pods they select, and flags budgets blocking evictions and
+// multi-replica workloads without a budget
+func (rp *ResourceProcessor) ShowPDBDetails(namespace string) error {
+	fmt.Println("\n[PodDisruptionBudget Layer]")
+	pdbs, err := rp.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting poddisruptionbudgets: %v", err)
+	}
+	podList, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+
+	// The budgets are checked by the lint rules, which need only the pods
+	lc := &lintContext{
+		namespace: namespace,
+		pods:      podList.Items,
+		pdbs:      pdbs.Items,
+		owners:    rp.newOwnerResolver(namespace),
+	}
+	lintFindings := runLintRules(lc, pdbLintRules...)
+	pods := runningPods(podList.Items)
+
+	for i, pdb := range pdbs.Items {
+		isLast := i == len(pdbs.Items)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "PDB", pdb.Name)
+		rp.formatter.Indent()
+
+		switch {
+		case pdb.Spec.MinAvailable != nil:
+			rp.formatter.PrintInfo("", "Min Available: %s", pdb.Spec.MinAvailable.String())
+		case pdb.Spec.MaxUnavailable != nil:
+			rp.formatter.PrintInfo("", "Max Unavailable: %s", pdb.Spec.MaxUnavailable.String())
+		}
+		if pdb.Spec.UnhealthyPodEvictionPolicy != nil {
+			rp.formatter.PrintInfo("", "Unhealthy Pod Eviction: %s", *pdb.Spec.UnhealthyPodEvictionPolicy)
+		}
+		rp.formatter.PrintStatus(fmt.Sprintf("Allowed Disruptions: %d", pdb.Status.DisruptionsAllowed),
+			pdb.Status.DisruptionsAllowed > 0)
+		rp.formatter.PrintInfo("", "Healthy Pods: %d/%d desired (%d expected)",
+			pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy, pdb.Status.ExpectedPods)
+
+		for _, group := range lc.owners.groupPodsByOwner(pdbSelectedPods(&pdb, pods)) {
+			ready := 0
+			for _, pod := range group.pods {
+				if isPodReady(pod) {
+					ready++
+				}
+			}
+			rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name,
+				fmt.Sprintf("Ready: %d/%d pods", ready, len(group.pods)))
+		}
+
+		if findings := findingsFor(lintFindings, "PodDisruptionBudget/"+pdb.Name); len(findings) > 0 {
+			rp.formatter.PrintInfo("", "Findings:")
+			printFindings(rp.formatter, findings)
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	var uncovered []Finding
+	for _, finding := range lintFindings {
+		if finding.ID == "workload-no-pdb" {
+			uncovered = append(uncovered, finding)
+		}
+	}
+	if len(uncovered) > 0 {
+		rp.formatter.PrintInfo("", "Workloads without a PodDisruptionBudget:")
+		printFindings(rp.formatter, uncovered)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// showServicePort prints a Service port mapping. Named target ports are
+// resolved against the container ports of the selected pods with the same
+// protocol, sidecars included, warning when the name resolves to different
+// numbers or to none at all.
+func (rp *ResourceProcessor) showServicePort(port corev1.ServicePort, pods []corev1.Pod) {
+	nodePort := ""
+	if port.NodePort > 0 {
+		nodePort = fmt.Sprintf(" (NodePort: %d)", port.NodePort)
+	}
+
+	if port.TargetPort.Type != intstr.String {
+		// An unset targetPort defaults to the Service port
+		target := port.TargetPort.IntVal
+		if target == 0 {
+			target = port.Port
+		}
+		rp.formatter.PrintInfo("", "Port: %d→%d/%s%s", port.Port, target, port.Protocol, nodePort)
+		return
+	}
+
+	name := port.TargetPort.StrVal
+	byNumber := make(map[int32][]string)
+	var unresolved []string
+	for i := range pods {
+		if number, ok := containerPortNumber(&pods[i], port); ok {
+			byNumber[number] = append(byNumber[number], pods[i].Name)
+		} else {
+			unresolved = append(unresolved, pods[i].Name)
+		}
+	}
+
+	numbers := make([]int32, 0, len(byNumber))
+	for number := range byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	target := name
+	switch {
+	case len(numbers) > 0:
+		resolved := make([]string, len(numbers))
+		for i, number := range numbers {
+			resolved[i] = fmt.Sprintf("%d", number)
+		}
+		target = fmt.Sprintf("%s (%s)", name, strings.Join(resolved, ", "))
+	case len(pods) > 0:
+		target = fmt.Sprintf("%s (unresolved)", name)
+	}
+	rp.formatter.PrintInfo("", "Port: %d→%s/%s%s", port.Port, target, port.Protocol, nodePort)
+
+	if len(numbers) > 1 {
+		var details []string
+		for _, number := range numbers {
+			details = append(details, fmt.Sprintf("%d on %s", number, strings.Join(byNumber[number], ", ")))
+		}
+		rp.formatter.PrintWarning("targetPort %s resolves differently across pods: %s", name, strings.Join(details, "; "))
+	}
+	if len(unresolved) > 0 {
+		rp.formatter.PrintWarning("targetPort %s matches no container port on %s", name, strings.Join(unresolved, ", "))
+	}
+}
@@ -0,0 +1,392 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// roleGrant is a RoleBinding or ClusterRoleBinding together with the rules of
+// the role it references
+type roleGrant struct {
+	kind      string
+	name      string
+	namespace string
+	role      string
+	subjects  []rbacv1.Subject
+	rules     []rbacv1.PolicyRule
+	// resolved is false when the referenced role is missing or not readable
+	resolved bool
+}
+
+// rbacIndex holds the bindings that grant permissions inside a namespace: its
+// RoleBindings and all ClusterRoleBindings
+type rbacIndex struct {
+	namespace string
+	grants    []roleGrant
+	// partial is set when RBAC objects could not be read; unreadable names
+	// their kinds
+	partial    bool
+	unreadable []string
+}
+
+func (rp *ResourceProcessor) loadRBACIndex(namespace string) *rbacIndex {
+	// Each list may be forbidden, so the index is built from what is readable
+	// and marked as partial otherwise
+	index := &rbacIndex{namespace: namespace}
+	unreadable := func(kind string) {
+		index.partial = true
+		index.unreadable = append(index.unreadable, kind)
+	}
+
+	rules := make(map[string][]rbacv1.PolicyRule)
+	if roles, err := rp.clientset.RbacV1().Roles(namespace).List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for _, role := range roles.Items {
+			rules["Role/"+role.Name] = role.Rules
+		}
+	} else {
+		unreadable("Roles")
+	}
+	if clusterRoles, err := rp.clientset.RbacV1().ClusterRoles().List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for _, role := range clusterRoles.Items {
+			rules["ClusterRole/"+role.Name] = role.Rules
+		}
+	} else {
+		unreadable("ClusterRoles")
+	}
+
+	addGrant := func(kind, name, bindingNamespace string, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) {
+		role := roleRef.Kind + "/" + roleRef.Name
+		roleRules, ok := rules[role]
+		index.grants = append(index.grants, roleGrant{
+			kind:      kind,
+			name:      name,
+			namespace: bindingNamespace,
+			role:      role,
+			subjects:  subjects,
+			rules:     roleRules,
+			resolved:  ok,
+		})
+	}
+	if roleBindings, err := rp.clientset.RbacV1().RoleBindings(namespace).List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for _, binding := range roleBindings.Items {
+			addGrant("RoleBinding", binding.Name, binding.Namespace, binding.RoleRef, binding.Subjects)
+		}
+	} else {
+		unreadable("RoleBindings")
+	}
+	if clusterBindings, err := rp.clientset.RbacV1().ClusterRoleBindings().List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for _, binding := range clusterBindings.Items {
+			addGrant("ClusterRoleBinding", binding.Name, "", binding.RoleRef, binding.Subjects)
+		}
+	} else {
+		unreadable("ClusterRoleBindings")
+	}
+
+	return index
+}
+
+// serviceAccountUser parses the username a ServiceAccount token authenticates
+// as, system:serviceaccount:<namespace>:<name>
+func serviceAccountUser(user string) (namespace, name string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(user, "system:serviceaccount:"), ":")
+	if !strings.HasPrefix(user, "system:serviceaccount:") || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// subjectMatches reports whether a binding subject refers to the given
+// ServiceAccount, directly or through one of its implicit groups
+func subjectMatches(subject rbacv1.Subject, bindingNamespace, saNamespace, saName string) bool {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		namespace := subject.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		return subject.Name == saName && namespace == saNamespace
+	case rbacv1.UserKind:
+		namespace, name, ok := serviceAccountUser(subject.Name)
+		return ok && name == saName && namespace == saNamespace
+	case rbacv1.GroupKind:
+		return subject.Name == "system:serviceaccounts" ||
+			subject.Name == "system:serviceaccounts:"+saNamespace ||
+			subject.Name == "system:authenticated"
+	}
+	return false
+}
+
+// grantsFor returns the grants that apply to a ServiceAccount
+func (ri *rbacIndex) grantsFor(saNamespace, saName string) []roleGrant {
+	var grants []roleGrant
+	for _, grant := range ri.grants {
+		for _, subject := range grant.subjects {
+			if subjectMatches(subject, grant.namespace, saNamespace, saName) {
+				grants = append(grants, grant)
+				break
+			}
+		}
+	}
+	return grants
+}
+
+func matchesRuleValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbacv1.VerbAll || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// secretReadVerbs are the verbs that expose Secret data
+var secretReadVerbs = []string{"get", "list", "watch"}
+
+// readsSecrets reports whether a rule grants read access to Secrets, returning
+// the Secret names it is restricted to
+func readsSecrets(rule rbacv1.PolicyRule) (bool, []string) {
+	for _, verb := range secretReadVerbs {
+		if matchesRuleValue(rule.Verbs, verb) && matchesRuleValue(rule.APIGroups, "") &&
+			matchesRuleValue(rule.Resources, "secrets") {
+			return true, rule.ResourceNames
+		}
+	}
+	return false, nil
+}
+
+func hasWildcard(rule rbacv1.PolicyRule) bool {
+	for _, values := range [][]string{rule.Verbs, rule.APIGroups, rule.Resources} {
+		for _, v := range values {
+			if v == rbacv1.VerbAll {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionSummary merges the rules of grants into effective verbs per
+// resource, marking permissions granted cluster-wide
+func permissionSummary(grants []roleGrant) []string {
+	verbs := make(map[string]map[string]bool)
+	for _, grant := range grants {
+		scope := ""
+		if grant.kind == "ClusterRoleBinding" {
+			scope = " (cluster-wide)"
+		}
+		for _, rule := range grant.rules {
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					qualified := resource
+					if group != "" {
+						qualified = resource + "." + group
+					}
+					keys := []string{qualified}
+					if len(rule.ResourceNames) > 0 {
+						keys = keys[:0]
+						for _, name := range rule.ResourceNames {
+							keys = append(keys, qualified+"/"+name)
+						}
+					}
+					for _, key := range keys {
+						key += scope
+						if verbs[key] == nil {
+							verbs[key] = make(map[string]bool)
+						}
+						for _, verb := range rule.Verbs {
+							verbs[key][verb] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var summary []string
+	for key, set := range verbs {
+		resource, scope, _ := strings.Cut(key, " ")
+		list := make([]string, 0, len(set))
+		for verb := range set {
+			list = append(list, verb)
+		}
+		sort.Strings(list)
+		line := fmt.Sprintf("%s: %s", resource, strings.Join(list, ", "))
+		if scope != "" {
+			line += " " + scope
+		}
+		summary = append(summary, line)
+	}
+	sort.Strings(summary)
+	return summary
+}
+
+// podServiceAccount returns the ServiceAccount a pod runs as
+func podServiceAccount(pod *corev1.Pod) string {
+	if pod.Spec.ServiceAccountName != "" {
+		return pod.Spec.ServiceAccountName
+	}
+	if pod.Spec.DeprecatedServiceAccount != "" {
+		return pod.Spec.DeprecatedServiceAccount
+	}
+	return "default"
+}
+
+// automountsToken reports whether the API token is mounted into a pod; the pod
+// setting takes precedence over the ServiceAccount one
+func automountsToken(pod *corev1.Pod, sa *corev1.ServiceAccount) bool {
+	if pod.Spec.AutomountServiceAccountToken != nil {
+		return *pod.Spec.AutomountServiceAccountToken
+	}
+	if sa != nil && sa.AutomountServiceAccountToken != nil {
+		return *sa.AutomountServiceAccountToken
+	}
+	return true
+}
+
+// ShowRBACDetails shows the ServiceAccount of each workload with the roles bound
+// to it and the resulting permissions, flagging Secret readers and wildcards
+func (rp *ResourceProcessor) ShowRBACDetails(namespace string) error {
+	fmt.Println("\n[RBAC Layer]")
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil
+	}
+	serviceAccounts, err := rp.clientset.CoreV1().ServiceAccounts(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting serviceaccounts: %v", err)
+	}
+	accounts := make(map[string]*corev1.ServiceAccount)
+	for i := range serviceAccounts.Items {
+		accounts[serviceAccounts.Items[i].Name] = &serviceAccounts.Items[i]
+	}
+	index := rp.loadRBACIndex(namespace)
+	if index.partial {
+		rp.formatter.PrintWarning("%s are not readable; permissions may be incomplete", strings.Join(index.unreadable, ", "))
+	}
+
+	groups := rp.newOwnerResolver(namespace).groupPodsByOwner(pods.Items)
+	for i, group := range groups {
+		isLast := i == len(groups)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, group.owner.Kind, group.owner.Name)
+		rp.formatter.Indent()
+
+		pod := group.pods[0]
+		saName := podServiceAccount(pod)
+		sa, found := accounts[saName]
+		automount := automountsToken(pod, sa)
+		rp.formatter.PrintRelation("ServiceAccount", saName, fmt.Sprintf("Automount Token: %t", automount))
+		if !found {
+			rp.formatter.PrintWarning("ServiceAccount %s not found", saName)
+		}
+
+		grants := index.grantsFor(namespace, saName)
+		if len(grants) == 0 {
+			rp.formatter.PrintInfo("", "No RBAC bindings")
+			rp.formatter.Outdent()
+			continue
+		}
+
+		for _, grant := range grants {
+			details := []string{fmt.Sprintf("Role: %s", grant.role)}
+			if !grant.resolved {
+				details = append(details, "Rules: unknown (role missing or not readable)")
+			}
+			rp.formatter.PrintRelation(grant.kind, grant.name, details...)
+		}
+
+		if summary := permissionSummary(grants); len(summary) > 0 {
+			rp.formatter.PrintInfo("", "Permissions:")
+			rp.formatter.Indent()
+			for _, line := range summary {
+				rp.formatter.PrintInfo("", "%s", line)
+			}
+			rp.formatter.Outdent()
+		}
+
+		for _, grant := range grants {
+			via := fmt.Sprintf("%s (%s/%s)", grant.role, grant.kind, grant.name)
+			allSecrets, wildcard := false, false
+			var secretNames []string
+			for _, rule := range grant.rules {
+				if reads, names := readsSecrets(rule); reads {
+					allSecrets = allSecrets || len(names) == 0
+					secretNames = append(secretNames, names...)
+				}
+				wildcard = wildcard || hasWildcard(rule)
+			}
+			if allSecrets {
+				rp.formatter.PrintWarning("Can read all Secrets via %s", via)
+			} else if len(secretNames) > 0 {
+				rp.formatter.PrintWarning("Can read Secrets %s via %s", strings.Join(secretNames, ", "), via)
+			}
+			if wildcard {
+				rp.formatter.PrintWarning("Wildcard permissions via %s", via)
+			}
+		}
+		if !automount {
+			rp.formatter.PrintInfo("", "API token not automounted; permissions apply only to explicitly mounted tokens")
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+// ruleAllows reports whether a policy rule permits a verb on a resource. An
+// empty name only matches rules that are not restricted to resourceNames.
+func ruleAllows(rule rbacv1.PolicyRule, verb, group, resource, name string) bool {
+	if !matchesRuleValue(rule.Verbs, verb) || !matchesRuleValue(rule.APIGroups, group) ||
+		!matchesRuleValue(rule.Resources, resource) {
+		return false
+	}
+	if len(rule.ResourceNames) == 0 {
+		return true
+	}
+	for _, resourceName := range rule.ResourceNames {
+		if name != "" && resourceName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// accessGrant is a grant together with the verbs it permits on one object
+type accessGrant struct {
+	roleGrant
+	verbs []string
+}
+
+// grantsAllowing returns the grants permitting any of the verbs on the named
+// object, with the verbs each of them permits
+func (ri *rbacIndex) grantsAllowing(verbs []string, group, resource, name string) []accessGrant {
+	var allowed []accessGrant
+	for _, grant := range ri.grants {
+		var permitted []string
+		for _, verb := range verbs {
+			for _, rule := range grant.rules {
+				if ruleAllows(rule, verb, group, resource, name) {
+					permitted = append(permitted, verb)
+					break
+				}
+			}
+		}
+		if len(permitted) > 0 {
+			allowed = append(allowed, accessGrant{roleGrant: grant, verbs: permitted})
+		}
+	}
+	return allowed
+}
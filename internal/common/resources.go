@@ -3,10 +3,11 @@ package common
 import (
 	"context"
 	"fmt"
+	"time"
 
-	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 )
 
 type ResourceProcessor struct {
@@ -14,6 +15,24 @@ type ResourceProcessor struct {
 	ctx       context.Context
 	formatter *Formatter
 	metrics   *ResourceMetrics
+	options   ProcessorOptions
+	dynamic   dynamic.Interface
+	// discovered caches discovery lookups by group version
+	discovered map[string]*metav1.APIResourceList
+}
+
+// ProcessorOptions enables the optional layers of ProcessNamespace
+type ProcessorOptions struct {
+	// Pricing enables the cost estimation layer when set
+	Pricing *PricingConfig
+	// CertExpiryWindow flags Ingress certificates expiring within this window
+	// (default 30 days when nil); zero flags only expired certificates
+	CertExpiryWindow *time.Duration
+	// IngressControllerNamespace is where the Ingress controller runs when checking
+	// NetworkPolicy reachability (default ingress-nginx)
+	IngressControllerNamespace string
+	// CustomResources enables the custom resource layer when set; it requires a dynamic client
+	CustomResources *CRDConfig
 }
 
 func NewResourceProcessor(clientset KubernetesClient, ctx context.Context) *ResourceProcessor {
@@ -26,6 +45,17 @@ func NewResourceProcessor(clientset KubernetesClient, ctx context.Context) *Reso
 	}
 }
 
+// SetOptions configures the optional layers shown by ProcessNamespace
+func (rp *ResourceProcessor) SetOptions(options ProcessorOptions) {
+	rp.options = options
+}
+
+// SetDynamicClient enables the layers built on resources outside the typed
+// clientset, such as the Gateway API
+func (rp *ResourceProcessor) SetDynamicClient(client dynamic.Interface) {
+	rp.dynamic = client
+}
+
 func (rp *ResourceProcessor) ShowDeploymentDetails(namespace string) error {
 	fmt.Println("\n[Deployment Layer]")
 	deployments, err := rp.clientset.AppsV1().Deployments(namespace).List(rp.ctx, metav1.ListOptions{})
@@ -61,34 +91,7 @@ func (rp *ResourceProcessor) ShowDeploymentDetails(namespace string) error {
 			}
 		}
 
-		// Show container details
-		for _, container := range deploy.Spec.Template.Spec.Containers {
-			rp.formatter.PrintInfo("", "Container: %s (Image: %s)", container.Name, container.Image)
-			for _, port := range container.Ports {
-				rp.formatter.PrintInfo("", "  Port: %d/%s", port.ContainerPort, port.Protocol)
-			}
-
-			// Show resources if defined
-			if container.Resources.Limits != nil || container.Resources.Requests != nil {
-				rp.formatter.PrintInfo("", "  Resources:")
-				if container.Resources.Requests != nil {
-					if cpu := container.Resources.Requests.Cpu(); cpu != nil {
-						rp.formatter.PrintInfo("", "    CPU Request: %s", cpu.String())
-					}
-					if memory := container.Resources.Requests.Memory(); memory != nil {
-						rp.formatter.PrintInfo("", "    Memory Request: %s", memory.String())
-					}
-				}
-				if container.Resources.Limits != nil {
-					if cpu := container.Resources.Limits.Cpu(); cpu != nil {
-						rp.formatter.PrintInfo("", "    CPU Limit: %s", cpu.String())
-					}
-					if memory := container.Resources.Limits.Memory(); memory != nil {
-						rp.formatter.PrintInfo("", "    Memory Limit: %s", memory.String())
-					}
-				}
-			}
-		}
+		rp.showContainers(&deploy.Spec.Template.Spec)
 
 		rp.formatter.Outdent()
 	}
@@ -96,61 +99,45 @@ func (rp *ResourceProcessor) ShowDeploymentDetails(namespace string) error {
 	return nil
 }
 
-func (rp *ResourceProcessor) ShowHPADetails(namespace string) error {
-	fmt.Println("\n[HPA Layer]")
-	hpas, err := rp.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(rp.ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("error getting HPAs: %v", err)
-	}
-
-	for i, hpa := range hpas.Items {
-		isLast := i == len(hpas.Items)-1
-		prefix := "├──"
-		if isLast {
-			prefix = "└──"
+// showContainers prints the images, ports and resources of a pod spec's
+// containers by type, with the effective pod requests when init containers,
+// sidecars or overhead change them
+func (rp *ResourceProcessor) showContainers(spec *corev1.PodSpec) {
+	for _, container := range podContainers(spec) {
+		rp.formatter.PrintInfo("", "%s: %s (Image: %s)", container.label(), container.Name, container.Image)
+		for _, port := range container.Ports {
+			rp.formatter.PrintInfo("", "  Port: %d/%s", port.ContainerPort, port.Protocol)
 		}
 
-		rp.formatter.PrintResource(prefix, "HPA", hpa.Name)
-		rp.formatter.Indent()
-
-		rp.formatter.PrintInfo("", "Target: %s/%s",
-			hpa.Spec.ScaleTargetRef.Kind,
-			hpa.Spec.ScaleTargetRef.Name)
-		rp.formatter.PrintInfo("", "Min Replicas: %d", *hpa.Spec.MinReplicas)
-		rp.formatter.PrintInfo("", "Max Replicas: %d", hpa.Spec.MaxReplicas)
-
-		for _, metric := range hpa.Spec.Metrics {
-			switch metric.Type {
-			case autoscalingv2.ResourceMetricSourceType:
-				if metric.Resource != nil {
-					rp.formatter.PrintInfo("", "Resource Metric: %s", metric.Resource.Name)
-					if metric.Resource.Target.AverageUtilization != nil {
-						rp.formatter.PrintInfo("", "  Target Utilization: %d%%",
-							*metric.Resource.Target.AverageUtilization)
-					}
-					if metric.Resource.Target.AverageValue != nil {
-						rp.formatter.PrintInfo("", "  Target Value: %s",
-							metric.Resource.Target.AverageValue.String())
-					}
+		// Show resources if defined
+		if container.Resources.Limits != nil || container.Resources.Requests != nil {
+			rp.formatter.PrintInfo("", "  Resources:")
+			if container.Resources.Requests != nil {
+				if cpu := container.Resources.Requests.Cpu(); cpu != nil {
+					rp.formatter.PrintInfo("", "    CPU Request: %s", cpu.String())
+				}
+				if memory := container.Resources.Requests.Memory(); memory != nil {
+					rp.formatter.PrintInfo("", "    Memory Request: %s", memory.String())
+				}
+			}
+			if container.Resources.Limits != nil {
+				if cpu := container.Resources.Limits.Cpu(); cpu != nil {
+					rp.formatter.PrintInfo("", "    CPU Limit: %s", cpu.String())
 				}
-			case autoscalingv2.PodsMetricSourceType:
-				if metric.Pods != nil {
-					rp.formatter.PrintInfo("", "Pods Metric: %s", metric.Pods.Metric.Name)
-					rp.formatter.PrintInfo("", "  Target Average Value: %s",
-						metric.Pods.Target.AverageValue.String())
+				if memory := container.Resources.Limits.Memory(); memory != nil {
+					rp.formatter.PrintInfo("", "    Memory Limit: %s", memory.String())
 				}
 			}
 		}
+	}
 
-		if hpa.Status.CurrentReplicas > 0 {
-			rp.formatter.PrintInfo("", "Current Replicas: %d", hpa.Status.CurrentReplicas)
-			rp.formatter.PrintInfo("", "Desired Replicas: %d", hpa.Status.DesiredReplicas)
+	if len(spec.InitContainers) > 0 || len(spec.Overhead) > 0 {
+		cpu, memory := podSpecRequests(spec)
+		if cpu > 0 || memory > 0 {
+			rp.formatter.PrintInfo("", "Effective Pod Requests: CPU %s, Memory %s",
+				rp.metrics.formatCPU(cpu), rp.metrics.formatMemory(memory))
 		}
-
-		rp.formatter.Outdent()
 	}
-
-	return nil
 }
 
 func (rp *ResourceProcessor) ShowConfigMapUsage(namespace string) error {
@@ -177,17 +164,22 @@ func (rp *ResourceProcessor) ShowConfigMapUsage(namespace string) error {
 			return fmt.Errorf("error getting pods: %v", err)
 		}
 
-		found := false
+		var consumers []corev1.Pod
 		for _, pod := range pods.Items {
 			usedAs := rp.getConfigMapUsageInPod(&pod, cm.Name)
 			if len(usedAs) > 0 {
-				if !found {
+				if len(consumers) == 0 {
 					rp.formatter.PrintInfo("", "Used by:")
-					found = true
 				}
+				consumers = append(consumers, pod)
 				rp.formatter.PrintRelation("Pod", pod.Name, usedAs...)
 			}
 		}
+		if len(consumers) > 0 {
+			rp.showKeyMatrix(namespace, sortedKeys(configMapKeys(&cm)), consumers, func(pod *corev1.Pod) []configUsage {
+				return configMapUsagesInPod(pod, cm.Name)
+			})
+		}
 
 		rp.formatter.Outdent()
 	}
@@ -201,6 +193,8 @@ func (rp *ResourceProcessor) ShowSecretUsage(namespace string) error {
 	if err != nil {
 		return fmt.Errorf("error getting secrets: %v", err)
 	}
+	sources := rp.loadSecretSources(namespace)
+	rp.warnPartialSources(sources)
 
 	for i, secret := range secrets.Items {
 		isLast := i == len(secrets.Items)-1
@@ -220,80 +214,63 @@ func (rp *ResourceProcessor) ShowSecretUsage(namespace string) error {
 			return fmt.Errorf("error getting pods: %v", err)
 		}
 
-		found := false
+		var consumers []corev1.Pod
 		for _, pod := range pods.Items {
-			usedAs := rp.getSecretUsageInPod(&pod, secret.Name)
+			usedAs := rp.getSecretUsageInPod(&pod, secret.Name, sources)
 			if len(usedAs) > 0 {
-				if !found {
+				if len(consumers) == 0 {
 					rp.formatter.PrintInfo("", "Used by:")
-					found = true
 				}
+				consumers = append(consumers, pod)
 				rp.formatter.PrintRelation("Pod", pod.Name, usedAs...)
 			}
 		}
-
-		rp.formatter.Outdent()
-	}
-
-	return nil
-}
-
-func (rp *ResourceProcessor) getConfigMapUsageInPod(pod *corev1.Pod, configMapName string) []string {
-	var usages []string
-
-	// Check volume mounts
-	for _, volume := range pod.Spec.Volumes {
-		if volume.ConfigMap != nil && volume.ConfigMap.Name == configMapName {
-			usages = append(usages, fmt.Sprintf("Mounted as volume: %s", volume.Name))
+		if len(consumers) > 0 {
+			rp.showKeyMatrix(namespace, sortedKeys(secretKeys(&secret)), consumers, func(pod *corev1.Pod) []configUsage {
+				return secretUsagesInPod(pod, secret.Name, sources)
+			})
 		}
-	}
 
-	// Check containers
-	for _, container := range pod.Spec.Containers {
-		for _, envFrom := range container.EnvFrom {
-			if envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == configMapName {
-				usages = append(usages, fmt.Sprintf("Used in envFrom by container: %s", container.Name))
+		if referrers := sources.referrers(&secret); len(referrers) > 0 {
+			rp.formatter.PrintInfo("", "Referenced by:")
+			for _, referrer := range referrers {
+				rp.formatter.PrintRelation(referrer.kind, referrer.name, referrer.details...)
 			}
 		}
 
-		for _, env := range container.Env {
-			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil &&
-				env.ValueFrom.ConfigMapKeyRef.Name == configMapName {
-				usages = append(usages, fmt.Sprintf("Used as env var '%s' in container: %s", env.Name, container.Name))
-			}
-		}
+		rp.formatter.Outdent()
 	}
 
-	return usages
+	return nil
 }
 
-func (rp *ResourceProcessor) getSecretUsageInPod(pod *corev1.Pod, secretName string) []string {
-	var usages []string
-
-	// Check volume mounts
-	for _, volume := range pod.Spec.Volumes {
-		if volume.Secret != nil && volume.Secret.SecretName == secretName {
-			usages = append(usages, fmt.Sprintf("Mounted as volume: %s", volume.Name))
-		}
+// ShowBrokenReferences reports Ingress, Service, ConfigMap, Secret and HPA
+// references that do not resolve to an existing object
+func (rp *ResourceProcessor) ShowBrokenReferences(namespace string) error {
+	fmt.Println("\n[Reference Check]")
+	findings, err := NewLinter(rp.clientset, rp.ctx, nil).LintReferences(namespace)
+	if err != nil {
+		rp.formatter.PrintWarning("Could not check references: %v", err)
+		return nil
 	}
 
-	// Check containers
-	for _, container := range pod.Spec.Containers {
-		for _, envFrom := range container.EnvFrom {
-			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName {
-				usages = append(usages, fmt.Sprintf("Used in envFrom by container: %s", container.Name))
-			}
-		}
-
-		for _, env := range container.Env {
-			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil &&
-				env.ValueFrom.SecretKeyRef.Name == secretName {
-				usages = append(usages, fmt.Sprintf("Used as env var '%s' in container: %s", env.Name, container.Name))
-			}
-		}
+	if len(findings) == 0 {
+		rp.formatter.PrintStatus("All references resolve", true)
+		return nil
 	}
+	printFindings(rp.formatter, findings)
+	return nil
+}
+
+// getConfigMapUsageInPod describes how the containers of a pod consume a ConfigMap
+func (rp *ResourceProcessor) getConfigMapUsageInPod(pod *corev1.Pod, configMapName string) []string {
+	return usageStrings(configMapUsagesInPod(pod, configMapName))
+}
 
-	return usages
+// getSecretUsageInPod describes how a pod consumes a Secret, including through
+// its ServiceAccount and secrets-store CSI volumes when sources is set
+func (rp *ResourceProcessor) getSecretUsageInPod(pod *corev1.Pod, secretName string, sources *secretSources) []string {
+	return usageStrings(secretUsagesInPod(pod, secretName, sources))
 }
 
 func (rp *ResourceProcessor) ProcessNamespace(namespace string) error {
@@ -305,18 +282,64 @@ func (rp *ResourceProcessor) ProcessNamespace(namespace string) error {
 		fmt.Printf("Warning: Could not fetch resource utilization: %v\n", err)
 	}
 
+	if rp.options.Pricing != nil {
+		if err := rp.metrics.ShowCostEstimation(namespace, rp.options.Pricing); err != nil {
+			fmt.Printf("Warning: Could not estimate cost: %v\n", err)
+		}
+	}
+
 	if err := rp.ShowResourceRelationships(namespace); err != nil {
 		return err
 	}
 
+	if err := rp.ShowMeshDetails(namespace); err != nil {
+		fmt.Printf("Warning: Could not fetch Istio resources: %v\n", err)
+	}
+
+	if err := rp.ShowNetworkPolicyDetails(namespace); err != nil {
+		fmt.Printf("Warning: Could not fetch network policies: %v\n", err)
+	}
+
+	if err := rp.ShowRBACDetails(namespace); err != nil {
+		fmt.Printf("Warning: Could not map RBAC permissions: %v\n", err)
+	}
+
 	if err := rp.ShowDeploymentDetails(namespace); err != nil {
 		return err
 	}
 
+	if err := rp.ShowStatefulSetDetails(namespace); err != nil {
+		return err
+	}
+
+	if err := rp.ShowDaemonSetDetails(namespace); err != nil {
+		return err
+	}
+
+	if err := rp.ShowReplicaSetDetails(namespace); err != nil {
+		return err
+	}
+
+	if err := rp.ShowBatchDetails(namespace); err != nil {
+		return err
+	}
+
+	if err := rp.ShowStorageDetails(namespace); err != nil {
+		return err
+	}
+
+	if err := rp.ShowCustomResources(namespace); err != nil {
+		fmt.Printf("Warning: Could not map custom resources: %v\n", err)
+	}
+
 	if err := rp.ShowHPADetails(namespace); err != nil {
 		return err
 	}
 
+	if err := rp.ShowPDBDetails(namespace); err != nil {
+		fmt.Printf("Warning: Could not fetch pod disruption budgets: %v\n", err)
+	}
+
 	if err := rp.ShowConfigMapUsage(namespace); err != nil {
 		return err
 	}
@@ -325,6 +348,10 @@ func (rp *ResourceProcessor) ProcessNamespace(namespace string) error {
 		return err
 	}
 
+	if err := rp.ShowBrokenReferences(namespace); err != nil {
+		return err
+	}
+
 	rp.formatter.PrintLine()
 	return nil
 }
@@ -342,7 +369,13 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 		return fmt.Errorf("error getting ingresses: %v", err)
 	}
 
+	// Only Services behind an Ingress receive traffic from the Ingress controller
+	exposed := make(map[string]bool)
 	for i, ingress := range ingresses.Items {
+		for _, backend := range ingressBackendServices(&ingresses.Items[i]) {
+			exposed[backend.Name] = true
+		}
+
 		isLast := i == len(ingresses.Items)-1
 		prefix := "├──"
 		if isLast {
@@ -359,6 +392,7 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 				rp.formatter.PrintInfo("", "Hosts: %v", tls.Hosts)
 				if tls.SecretName != "" {
 					rp.formatter.PrintInfo("", "TLS Secret: %s", tls.SecretName)
+					rp.showCertificate(&ingress, tls)
 				}
 			}
 		}
@@ -370,7 +404,9 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 					details := []string{
 						fmt.Sprintf("via host: %s", rule.Host),
 						fmt.Sprintf("path: %s", path.Path),
-						fmt.Sprintf("pathType: %s", path.PathType),
+					}
+					if path.PathType != nil {
+						details = append(details, fmt.Sprintf("pathType: %s", *path.PathType))
 					}
 
 					if path.Backend.Service != nil {
@@ -385,6 +421,11 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 		rp.formatter.Outdent()
 	}
 
+	// Gateway API routes are the successor of Ingress and shown when installed
+	if err := rp.ShowGatewayDetails(namespace); err != nil {
+		fmt.Printf("Warning: Could not fetch Gateway API resources: %v\n", err)
+	}
+
 	// Handle Services
 	fmt.Println("\n[Service Layer]")
 	services, err := rp.clientset.CoreV1().Services(namespace).List(rp.ctx, metav1.ListOptions{})
@@ -392,6 +433,32 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 		return fmt.Errorf("error getting services: %v", err)
 	}
 
+	// NetworkPolicies decide whether the Ingress controller can reach each Service's pods
+	policies, err := rp.loadPolicyIndex(namespace)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch network policies: %v\n", err)
+	}
+	var controller trafficSource
+	if policies != nil && len(policies.policies) > 0 && len(exposed) > 0 {
+		controller = rp.ingressControllerSource()
+	}
+
+	// Zones with nodes show where a Service has no ready endpoints
+	var nodeZones map[string]bool
+	if len(services.Items) > 0 {
+		if nodeZones, err = rp.loadNodeZones(); err != nil {
+			fmt.Printf("Warning: Could not fetch nodes: %v\n", err)
+		}
+	}
+
+	// Istio VirtualServices split Service traffic across subsets of its pods
+	mesh, err := rp.loadMeshIndex(namespace)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch Istio resources: %v\n", err)
+	} else if mesh != nil && mesh.fallback != nil {
+		fmt.Printf("Warning: Could not list Istio resources in all namespaces, showing %s only: %v\n", namespace, mesh.fallback)
+	}
+
 	for i, service := range services.Items {
 		isLast := i == len(services.Items)-1
 		prefix := "├──"
@@ -411,57 +478,40 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 			rp.formatter.PrintInfo("", "External IPs: %v", service.Spec.ExternalIPs)
 		}
 
-		// Show port mappings
-		for _, port := range service.Spec.Ports {
-			portInfo := fmt.Sprintf("Port: %d→%d/%s", port.Port, port.TargetPort.IntVal, port.Protocol)
-			if port.NodePort > 0 {
-				portInfo += fmt.Sprintf(" (NodePort: %d)", port.NodePort)
+		// The selected pods resolve named target ports and are listed below
+		var selected []corev1.Pod
+		if len(service.Spec.Selector) > 0 {
+			pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{
+				LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{
+					MatchLabels: service.Spec.Selector,
+				}),
+			})
+			if err != nil {
+				return fmt.Errorf("error getting pods for service %s: %v", service.Name, err)
 			}
-			rp.formatter.PrintInfo("", portInfo)
+			selected = pods.Items
 		}
 
-		// Show endpoints if they exist
-		endpoints, err := rp.clientset.CoreV1().Endpoints(namespace).Get(rp.ctx, service.Name, metav1.GetOptions{})
-		if err == nil && len(endpoints.Subsets) > 0 {
-			rp.formatter.PrintInfo("", "Endpoints:")
-			for _, subset := range endpoints.Subsets {
-				for _, addr := range subset.Addresses {
-					target := ""
-					if addr.TargetRef != nil {
-						target = fmt.Sprintf(" (%s: %s)", addr.TargetRef.Kind, addr.TargetRef.Name)
-					}
-					rp.formatter.PrintInfo("", "  %s%s", addr.IP, target)
-				}
-			}
+		// Show port mappings
+		for _, port := range service.Spec.Ports {
+			rp.showServicePort(port, selected)
 		}
 
+		// Show endpoints from the EndpointSlices of the Service
+		rp.showEndpointSlices(namespace, &service, nodeZones)
+
 		// Show selector and find matching pods
 		if len(service.Spec.Selector) > 0 {
 			rp.formatter.PrintInfo("", "Selector: %v", service.Spec.Selector)
 
-			labelSelector := metav1.FormatLabelSelector(&metav1.LabelSelector{
-				MatchLabels: service.Spec.Selector,
-			})
-			pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{
-				LabelSelector: labelSelector,
-			})
-			if err != nil {
-				return fmt.Errorf("error getting pods for service %s: %v", service.Name, err)
-			}
-
-			if len(pods.Items) > 0 {
+			if len(selected) > 0 {
 				rp.formatter.PrintInfo("", "Connected Pods:")
-				for _, pod := range pods.Items {
-					details := []string{
-						fmt.Sprintf("Status: %s", pod.Status.Phase),
-					}
-					if pod.Status.PodIP != "" {
-						details = append(details, fmt.Sprintf("IP: %s", pod.Status.PodIP))
-					}
-					if pod.Spec.NodeName != "" {
-						details = append(details, fmt.Sprintf("Node: %s", pod.Spec.NodeName))
-					}
-					rp.formatter.PrintRelation("Pod", pod.Name, details...)
+				rp.showConnectedPods(namespace, selected)
+				if exposed[service.Name] {
+					rp.showIngressReachability(policies, controller, &service, selected)
+				}
+				if mesh != nil {
+					rp.showMeshRoutes(mesh, &service, selected)
 				}
 			} else {
 				rp.formatter.PrintStatus("No pods found matching selector", false)
@@ -469,39 +519,28 @@ func (rp *ResourceProcessor) ShowResourceRelationships(namespace string) error {
 		}
 
 		// Show resource requirements if defined
-		if len(service.Spec.Selector) > 0 {
-			pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{
-				LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{
-					MatchLabels: service.Spec.Selector,
-				}),
-			})
-			if err == nil && len(pods.Items) > 0 {
-				var totalCPURequest, totalMemoryRequest int64
-				var totalCPULimit, totalMemoryLimit int64
-
-				for _, pod := range pods.Items {
-					for _, container := range pod.Spec.Containers {
-						if container.Resources.Requests != nil {
-							totalCPURequest += container.Resources.Requests.Cpu().MilliValue()
-							totalMemoryRequest += container.Resources.Requests.Memory().Value()
-						}
-						if container.Resources.Limits != nil {
-							totalCPULimit += container.Resources.Limits.Cpu().MilliValue()
-							totalMemoryLimit += container.Resources.Limits.Memory().Value()
-						}
-					}
-				}
+		if len(selected) > 0 {
+			var totalCPURequest, totalMemoryRequest int64
+			var totalCPULimit, totalMemoryLimit int64
+
+			for _, pod := range selected {
+				cpu, memory := podRequests(&pod)
+				totalCPURequest += cpu
+				totalMemoryRequest += memory
+				cpu, memory = podLimits(&pod)
+				totalCPULimit += cpu
+				totalMemoryLimit += memory
+			}
 
-				if totalCPURequest > 0 || totalMemoryRequest > 0 {
-					rp.formatter.PrintInfo("", "Total Resource Requests:")
-					rp.formatter.PrintInfo("", "  CPU: %dm", totalCPURequest)
-					rp.formatter.PrintInfo("", "  Memory: %dMi", totalMemoryRequest/(1024*1024))
-				}
-				if totalCPULimit > 0 || totalMemoryLimit > 0 {
-					rp.formatter.PrintInfo("", "Total Resource Limits:")
-					rp.formatter.PrintInfo("", "  CPU: %dm", totalCPULimit)
-					rp.formatter.PrintInfo("", "  Memory: %dMi", totalMemoryLimit/(1024*1024))
-				}
+			if totalCPURequest > 0 || totalMemoryRequest > 0 {
+				rp.formatter.PrintInfo("", "Total Resource Requests:")
+				rp.formatter.PrintInfo("", "  CPU: %dm", totalCPURequest)
+				rp.formatter.PrintInfo("", "  Memory: %dMi", totalMemoryRequest/(1024*1024))
+			}
+			if totalCPULimit > 0 || totalMemoryLimit > 0 {
+				rp.formatter.PrintInfo("", "Total Resource Limits:")
+				rp.formatter.PrintInfo("", "  CPU: %dm", totalCPULimit)
+				rp.formatter.PrintInfo("", "  Memory: %dMi", totalMemoryLimit/(1024*1024))
 			}
 		}
 
@@ -0,0 +1,215 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// persistentVolumeSource describes where a PersistentVolume's data lives
+func persistentVolumeSource(pv *corev1.PersistentVolume) string {
+	source := pv.Spec.PersistentVolumeSource
+	switch {
+	case source.CSI != nil:
+		return fmt.Sprintf("CSI %s (%s)", source.CSI.Driver, source.CSI.VolumeHandle)
+	case source.NFS != nil:
+		return fmt.Sprintf("NFS %s:%s", source.NFS.Server, source.NFS.Path)
+	case source.HostPath != nil:
+		return fmt.Sprintf("HostPath %s", source.HostPath.Path)
+	case source.Local != nil:
+		return fmt.Sprintf("Local %s", source.Local.Path)
+	case source.AWSElasticBlockStore != nil:
+		return fmt.Sprintf("AWS EBS %s", source.AWSElasticBlockStore.VolumeID)
+	case source.GCEPersistentDisk != nil:
+		return fmt.Sprintf("GCE PD %s", source.GCEPersistentDisk.PDName)
+	case source.AzureDisk != nil:
+		return fmt.Sprintf("Azure Disk %s", source.AzureDisk.DiskName)
+	case source.AzureFile != nil:
+		return fmt.Sprintf("Azure File %s", source.AzureFile.ShareName)
+	case source.CephFS != nil:
+		return "CephFS"
+	case source.RBD != nil:
+		return fmt.Sprintf("RBD %s/%s", source.RBD.RBDPool, source.RBD.RBDImage)
+	case source.ISCSI != nil:
+		return fmt.Sprintf("iSCSI %s", source.ISCSI.TargetPortal)
+	}
+	return "unknown"
+}
+
+// volumeClaimName returns the PersistentVolumeClaim a pod volume is backed by.
+// A generic ephemeral volume gets a claim named after the pod and the volume
+func volumeClaimName(pod *corev1.Pod, volume corev1.Volume) (string, bool) {
+	switch {
+	case volume.PersistentVolumeClaim != nil:
+		return volume.PersistentVolumeClaim.ClaimName, true
+	case volume.Ephemeral != nil:
+		return pod.Name + "-" + volume.Name, true
+	}
+	return "", false
+}
+
+// getPVCUsageInPod returns how a pod uses a PersistentVolumeClaim
+func getPVCUsageInPod(pod *corev1.Pod, claimName string) []string {
+	var usages []string
+	for _, volume := range pod.Spec.Volumes {
+		if name, ok := volumeClaimName(pod, volume); !ok || name != claimName {
+			continue
+		}
+		for _, container := range allContainers(&pod.Spec) {
+			for _, mount := range container.VolumeMounts {
+				if mount.Name != volume.Name {
+					continue
+				}
+				mode := "rw"
+				if mount.ReadOnly || (volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ReadOnly) {
+					mode = "ro"
+				}
+				usages = append(usages, fmt.Sprintf("%s:%s (%s) in container %s", pod.Name, mount.MountPath, mode, container.Name))
+			}
+		}
+	}
+	return usages
+}
+
+func (rp *ResourceProcessor) ShowStorageDetails(namespace string) error {
+	fmt.Println("\n[Storage Layer]")
+	claims, err := rp.clientset.CoreV1().PersistentVolumeClaims(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting persistentvolumeclaims: %v", err)
+	}
+	if len(claims.Items) == 0 {
+		return nil
+	}
+
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+
+	// PersistentVolumes and StorageClasses are cluster-scoped and may not be
+	// readable with namespace-level permissions, so their details are optional
+	volumes := make(map[string]*corev1.PersistentVolume)
+	if pvList, err := rp.clientset.CoreV1().PersistentVolumes().List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for i := range pvList.Items {
+			volumes[pvList.Items[i].Name] = &pvList.Items[i]
+		}
+	}
+	classes := make(map[string]*storagev1.StorageClass)
+	defaultClass := ""
+	if scList, err := rp.clientset.StorageV1().StorageClasses().List(rp.ctx, metav1.ListOptions{}); err == nil {
+		for i := range scList.Items {
+			sc := &scList.Items[i]
+			classes[sc.Name] = sc
+			if sc.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
+				defaultClass = sc.Name
+			}
+		}
+	}
+
+	resolver := rp.newOwnerResolver(namespace)
+
+	for i, claim := range claims.Items {
+		isLast := i == len(claims.Items)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "PersistentVolumeClaim", claim.Name)
+		rp.formatter.Indent()
+
+		rp.formatter.PrintStatus(fmt.Sprintf("Status: %s", claim.Status.Phase), claim.Status.Phase == corev1.ClaimBound)
+
+		if storage, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			capacity := "-"
+			if actual, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+				capacity = actual.String()
+			}
+			rp.formatter.PrintInfo("", "Capacity: %s (requested %s)", capacity, storage.String())
+		}
+		rp.formatter.PrintInfo("", "Access Modes: %v", claim.Spec.AccessModes)
+		if claim.Spec.VolumeMode != nil && *claim.Spec.VolumeMode != corev1.PersistentVolumeFilesystem {
+			rp.formatter.PrintInfo("", "Volume Mode: %s", *claim.Spec.VolumeMode)
+		}
+
+		// A claim without a class is only assigned the default while pending; a
+		// bound one stays classless whatever the default is now
+		className := ""
+		if claim.Spec.StorageClassName != nil {
+			className = *claim.Spec.StorageClassName
+		} else if claim.Status.Phase != corev1.ClaimBound {
+			className = defaultClass
+		}
+		if className != "" {
+			details := []string{}
+			if sc, ok := classes[className]; ok {
+				details = append(details, fmt.Sprintf("Provisioner: %s", sc.Provisioner))
+				if sc.ReclaimPolicy != nil {
+					details = append(details, fmt.Sprintf("Reclaim Policy: %s", *sc.ReclaimPolicy))
+				}
+				if sc.VolumeBindingMode != nil {
+					details = append(details, fmt.Sprintf("Binding Mode: %s", *sc.VolumeBindingMode))
+				}
+				details = append(details, fmt.Sprintf("Allow Expansion: %t", sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion))
+			}
+			rp.formatter.PrintRelation("StorageClass", className, details...)
+		}
+
+		if claim.Spec.VolumeName != "" {
+			details := []string{}
+			if pv, ok := volumes[claim.Spec.VolumeName]; ok {
+				details = append(details,
+					fmt.Sprintf("Source: %s", persistentVolumeSource(pv)),
+					fmt.Sprintf("Reclaim Policy: %s", pv.Spec.PersistentVolumeReclaimPolicy),
+					fmt.Sprintf("Status: %s", pv.Status.Phase))
+			}
+			rp.formatter.PrintRelation("PersistentVolume", claim.Spec.VolumeName, details...)
+		}
+
+		// Collect consuming pods and collapse them by workload
+		var consumers []corev1.Pod
+		mounts := make(map[string][]string)
+		for _, pod := range pods.Items {
+			if usages := getPVCUsageInPod(&pod, claim.Name); len(usages) > 0 {
+				consumers = append(consumers, pod)
+				mounts[pod.Name] = usages
+			}
+		}
+		if len(consumers) == 0 {
+			rp.formatter.PrintWarning("Not mounted by any pod")
+		} else {
+			rp.formatter.PrintInfo("", "Used by:")
+			for _, group := range resolver.groupPodsByOwner(consumers) {
+				var details []string
+				for _, pod := range group.pods {
+					details = append(details, mounts[pod.Name]...)
+				}
+				rp.formatter.PrintRelation(group.owner.Kind, group.owner.Name, details...)
+			}
+		}
+
+		if claim.Status.Phase == corev1.ClaimBound && len(consumers) > 1 {
+			for _, mode := range claim.Spec.AccessModes {
+				if mode == corev1.ReadWriteOncePod {
+					rp.formatter.PrintWarning("ReadWriteOncePod claim mounted by %d pods: %s",
+						len(consumers), strings.Join(podNames(consumers), ", "))
+				}
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+func podNames(pods []corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultCertExpiryWindow is used when ProcessorOptions.CertExpiryWindow is unset
+const defaultCertExpiryWindow = 30 * 24 * time.Hour
+
+// CertificateInfo summarizes the leaf certificate of a TLS Secret
+type CertificateInfo struct {
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	IPs       []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// ParseCertificate parses the first certificate of a PEM bundle, which by
+// convention is the leaf certificate in tls.crt
+func ParseCertificate(pemData []byte) (*CertificateInfo, error) {
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %v", err)
+		}
+
+		info := &CertificateInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		}
+		for _, ip := range cert.IPAddresses {
+			info.IPs = append(info.IPs, ip.String())
+		}
+		return info, nil
+	}
+}
+
+// Covers reports whether host matches one of the certificate's SANs. A wildcard
+// SAN matches exactly one left-most label, as browsers do.
+func (c *CertificateInfo) Covers(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, ip := range c.IPs {
+		if ip == host {
+			return true
+		}
+	}
+	for _, name := range c.DNSNames {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == host {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") {
+			dot := strings.Index(host, ".")
+			if dot > 0 && host[dot+1:] == name[2:] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExpiresWithin reports whether the certificate expires within the given window of now
+func (c *CertificateInfo) ExpiresWithin(now time.Time, window time.Duration) bool {
+	return c.NotAfter.Before(now.Add(window))
+}
+
+// ingressTLSHosts returns the hosts of a TLS entry, defaulting to the hosts of the
+// Ingress rules when the entry lists none
+func ingressTLSHosts(ingress *networkingv1.Ingress, tls networkingv1.IngressTLS) []string {
+	if len(tls.Hosts) > 0 {
+		return tls.Hosts
+	}
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+// showCertificate inspects the certificate of an Ingress TLS Secret
+func (rp *ResourceProcessor) showCertificate(ingress *networkingv1.Ingress, tls networkingv1.IngressTLS) {
+	secret, err := rp.clientset.CoreV1().Secrets(ingress.Namespace).Get(rp.ctx, tls.SecretName, metav1.GetOptions{})
+	if err != nil {
+		rp.formatter.PrintStatus(fmt.Sprintf("Could not load TLS Secret %s: %v", tls.SecretName, err), false)
+		return
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		rp.formatter.PrintWarning("Secret %s has type %s, expected %s", secret.Name, secret.Type, corev1.SecretTypeTLS)
+	}
+
+	cert, err := ParseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		rp.formatter.PrintStatus(fmt.Sprintf("Invalid certificate in %s: %v", secret.Name, err), false)
+		return
+	}
+
+	rp.formatter.Indent()
+	rp.formatter.PrintInfo("", "Subject: %s", cert.Subject)
+	rp.formatter.PrintInfo("", "Issuer: %s", cert.Issuer)
+	if sans := append(append([]string{}, cert.DNSNames...), cert.IPs...); len(sans) > 0 {
+		rp.formatter.PrintInfo("", "SANs: %s", strings.Join(sans, ", "))
+	}
+
+	now := time.Now()
+	window := defaultCertExpiryWindow
+	if rp.options.CertExpiryWindow != nil {
+		window = *rp.options.CertExpiryWindow
+	}
+	expires := cert.NotAfter.Format("2006-01-02 15:04:05")
+	switch {
+	case now.After(cert.NotAfter):
+		rp.formatter.PrintStatus(fmt.Sprintf("Expired: %s (%s ago)", expires, formatAge(now.Sub(cert.NotAfter))), false)
+	case cert.ExpiresWithin(now, window):
+		rp.formatter.PrintWarning("Expires: %s (in %s)", expires, formatAge(cert.NotAfter.Sub(now)))
+	default:
+		rp.formatter.PrintStatus(fmt.Sprintf("Expires: %s (in %s)", expires, formatAge(cert.NotAfter.Sub(now))), true)
+	}
+	if now.Before(cert.NotBefore) {
+		rp.formatter.PrintWarning("Not valid before %s", cert.NotBefore.Format("2006-01-02 15:04:05"))
+	}
+
+	for _, host := range ingressTLSHosts(ingress, tls) {
+		if !cert.Covers(host) {
+			rp.formatter.PrintStatus(fmt.Sprintf("Host %s not covered by certificate", host), false)
+		}
+	}
+	rp.formatter.Outdent()
+}
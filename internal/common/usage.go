@@ -0,0 +1,502 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Ways a pod consumes a ConfigMap or Secret
+const (
+	usageVolume      = "volume"
+	usageProjected   = "projected"
+	usageSecretStore = "secretStore"
+	usageCSISecret   = "csiSecret"
+	usageImagePull   = "imagePull"
+	usageEnvFrom     = "envFrom"
+	usageEnv         = "env"
+)
+
+const (
+	secretsStoreGroup  = "secrets-store.csi.x-k8s.io"
+	secretsStoreDriver = "secrets-store.csi.k8s.io"
+)
+
+// configUsage is one way a pod consumes a ConfigMap or Secret
+type configUsage struct {
+	source string
+	// container and containerType are empty for pod-level usage and for a
+	// volume no container mounts
+	container     string
+	containerType string
+	volume        string
+	mountPath     string
+	subPath       string
+	envVar        string
+	// keys are the data keys read, nil when the whole object is consumed
+	keys []string
+	// optional usages tolerate a missing object or key
+	optional bool
+	// providerClass is the SecretProviderClass syncing the Secret
+	providerClass string
+	// serviceAccount is set when the usage comes from the pod's ServiceAccount
+	serviceAccount string
+}
+
+func (u configUsage) String() string {
+	switch u.source {
+	case usageEnvFrom:
+		return fmt.Sprintf("Used in envFrom by %s: %s", u.containerType, u.container)
+	case usageEnv:
+		return fmt.Sprintf("Used as env var '%s' in %s: %s", u.envVar, u.containerType, u.container)
+	case usageImagePull:
+		if u.serviceAccount != "" {
+			return fmt.Sprintf("Used as imagePullSecret via ServiceAccount: %s", u.serviceAccount)
+		}
+		return "Used as imagePullSecret"
+	case usageCSISecret:
+		return fmt.Sprintf("Used as node publish secret of CSI volume: %s", u.volume)
+	}
+
+	description := fmt.Sprintf("Mounted as volume: %s", u.volume)
+	switch u.source {
+	case usageProjected:
+		description = fmt.Sprintf("Mounted as projected volume: %s", u.volume)
+	case usageSecretStore:
+		description = fmt.Sprintf("Synced by SecretProviderClass %s into volume: %s", u.providerClass, u.volume)
+	}
+	if u.container == "" {
+		return description + " (not mounted by any container)"
+	}
+	mountPath := u.mountPath
+	if u.subPath != "" {
+		mountPath += fmt.Sprintf(" (subPath %s)", u.subPath)
+	}
+	return fmt.Sprintf("%s at %s in %s: %s", description, mountPath, u.containerType, u.container)
+}
+
+// keySelection is the part of a ConfigMap or Secret a reference selects;
+// nil items select every key
+type keySelection struct {
+	items    []corev1.KeyToPath
+	optional bool
+}
+
+// newKeySelection builds the selection of a reference with an optional flag
+func newKeySelection(items []corev1.KeyToPath, optional *bool) keySelection {
+	return keySelection{items: items, optional: optional != nil && *optional}
+}
+
+// keys returns the selected keys, or nil when every key is selected
+func (s keySelection) keys() []string {
+	if s.items == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(s.items))
+	for _, item := range s.items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+// mountedKeys returns the keys a volume mount exposes: the file picked by a
+// subPath, or the whole selection
+func (s keySelection) mountedKeys(subPath string) []string {
+	if subPath == "" {
+		return s.keys()
+	}
+	if s.items == nil {
+		return []string{subPath}
+	}
+	keys := []string{}
+	for _, item := range s.items {
+		if item.Path == subPath || strings.HasPrefix(item.Path, subPath+"/") {
+			keys = append(keys, item.Key)
+		}
+	}
+	return keys
+}
+
+// configReference matches the references a pod spec makes to one ConfigMap or Secret
+type configReference struct {
+	volume    func(corev1.Volume) (keySelection, bool)
+	projected func(corev1.VolumeProjection) (keySelection, bool)
+	envFrom   func(corev1.EnvFromSource) (keySelection, bool)
+	env       func(*corev1.EnvVarSource) (keySelection, bool)
+	// secret is the name of a referenced Secret, which pods may also use for
+	// image pulls and CSI node publishing
+	secret string
+}
+
+func configMapReference(name string) configReference {
+	return configReference{
+		volume: func(volume corev1.Volume) (keySelection, bool) {
+			if volume.ConfigMap == nil || volume.ConfigMap.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(volume.ConfigMap.Items, volume.ConfigMap.Optional), true
+		},
+		projected: func(source corev1.VolumeProjection) (keySelection, bool) {
+			if source.ConfigMap == nil || source.ConfigMap.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(source.ConfigMap.Items, source.ConfigMap.Optional), true
+		},
+		envFrom: func(envFrom corev1.EnvFromSource) (keySelection, bool) {
+			if envFrom.ConfigMapRef == nil || envFrom.ConfigMapRef.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(nil, envFrom.ConfigMapRef.Optional), true
+		},
+		env: func(source *corev1.EnvVarSource) (keySelection, bool) {
+			ref := source.ConfigMapKeyRef
+			if ref == nil || ref.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection([]corev1.KeyToPath{{Key: ref.Key}}, ref.Optional), true
+		},
+	}
+}
+
+func secretReference(name string) configReference {
+	return configReference{
+		volume: func(volume corev1.Volume) (keySelection, bool) {
+			if volume.Secret == nil || volume.Secret.SecretName != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(volume.Secret.Items, volume.Secret.Optional), true
+		},
+		projected: func(source corev1.VolumeProjection) (keySelection, bool) {
+			if source.Secret == nil || source.Secret.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(source.Secret.Items, source.Secret.Optional), true
+		},
+		envFrom: func(envFrom corev1.EnvFromSource) (keySelection, bool) {
+			if envFrom.SecretRef == nil || envFrom.SecretRef.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection(nil, envFrom.SecretRef.Optional), true
+		},
+		env: func(source *corev1.EnvVarSource) (keySelection, bool) {
+			ref := source.SecretKeyRef
+			if ref == nil || ref.Name != name {
+				return keySelection{}, false
+			}
+			return newKeySelection([]corev1.KeyToPath{{Key: ref.Key}}, ref.Optional), true
+		},
+		secret: name,
+	}
+}
+
+// volumeUsages returns a usage per container mounting a volume, or a single
+// container-less usage when no container mounts it
+func volumeUsages(containers []typedContainer, volume string, selection keySelection, usage configUsage) []configUsage {
+	usage.optional = selection.optional
+	var usages []configUsage
+	for _, container := range containers {
+		for _, mount := range container.VolumeMounts {
+			if mount.Name != volume {
+				continue
+			}
+			mounted := usage
+			mounted.container = container.Name
+			mounted.containerType = container.containerType
+			mounted.mountPath = mount.MountPath
+			mounted.subPath = mount.SubPath
+			mounted.keys = selection.mountedKeys(mount.SubPath)
+			usages = append(usages, mounted)
+		}
+	}
+	if len(usages) == 0 {
+		usage.keys = selection.keys()
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// podConfigUsages returns how the containers of a pod spec consume the object
+// matched by ref, covering init, sidecar, app and ephemeral containers
+func podConfigUsages(spec *corev1.PodSpec, ref configReference) []configUsage {
+	var usages []configUsage
+	containers := podContainers(spec)
+
+	if ref.secret != "" {
+		for _, pullSecret := range spec.ImagePullSecrets {
+			if pullSecret.Name == ref.secret {
+				usages = append(usages, configUsage{source: usageImagePull})
+			}
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		if selection, ok := ref.volume(volume); ok {
+			usages = append(usages, volumeUsages(containers, volume.Name, selection,
+				configUsage{source: usageVolume, volume: volume.Name})...)
+		}
+		switch {
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if selection, ok := ref.projected(source); ok {
+					usages = append(usages, volumeUsages(containers, volume.Name, selection,
+						configUsage{source: usageProjected, volume: volume.Name})...)
+				}
+			}
+		case volume.CSI != nil && ref.secret != "":
+			if volume.CSI.NodePublishSecretRef != nil && volume.CSI.NodePublishSecretRef.Name == ref.secret {
+				usages = append(usages, configUsage{source: usageCSISecret, volume: volume.Name})
+			}
+		}
+	}
+
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if selection, ok := ref.envFrom(envFrom); ok {
+				usages = append(usages, configUsage{
+					source:        usageEnvFrom,
+					container:     container.Name,
+					containerType: container.containerType,
+					optional:      selection.optional,
+				})
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if selection, ok := ref.env(env.ValueFrom); ok {
+				usages = append(usages, configUsage{
+					source:        usageEnv,
+					container:     container.Name,
+					containerType: container.containerType,
+					envVar:        env.Name,
+					keys:          selection.keys(),
+					optional:      selection.optional,
+				})
+			}
+		}
+	}
+
+	return usages
+}
+
+// configMapUsagesInPod returns how the containers of a pod consume a ConfigMap
+func configMapUsagesInPod(pod *corev1.Pod, name string) []configUsage {
+	return podConfigUsages(&pod.Spec, configMapReference(name))
+}
+
+// secretUsagesInPod returns how a pod consumes a Secret, including through its
+// ServiceAccount and secrets-store CSI volumes when sources is set
+func secretUsagesInPod(pod *corev1.Pod, name string, sources *secretSources) []configUsage {
+	usages := podConfigUsages(&pod.Spec, secretReference(name))
+	if sources != nil {
+		usages = append(usages, sources.podUsages(pod, name)...)
+	}
+	return usages
+}
+
+// usageStrings describes each usage on its own line
+func usageStrings(usages []configUsage) []string {
+	var lines []string
+	for _, usage := range usages {
+		lines = append(lines, usage.String())
+	}
+	return lines
+}
+
+// secretProviderClass is the subset of the secrets-store CSI driver schema
+// microlens needs, decoded from unstructured objects
+type secretProviderClass struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Provider      string `json:"provider"`
+		SecretObjects []struct {
+			SecretName string `json:"secretName"`
+			Data       []struct {
+				Key string `json:"key"`
+			} `json:"data"`
+		} `json:"secretObjects"`
+	} `json:"spec"`
+}
+
+// secretSources holds the objects of a namespace that reference Secrets
+// outside pod specs: ServiceAccounts, Ingress TLS entries and
+// SecretProviderClasses syncing Secrets from an external store
+type secretSources struct {
+	serviceAccounts []corev1.ServiceAccount
+	ingresses       []networkingv1.Ingress
+	providerClasses []*secretProviderClass
+	// partial is set when some sources could not be read; unreadable names
+	// their kinds and err holds the first error
+	partial    bool
+	unreadable []string
+	err        error
+}
+
+// loadSecretSources lists the ServiceAccounts, Ingresses and, when the
+// secrets-store CSI driver is installed, the SecretProviderClasses of a
+// namespace. Each list may be forbidden, so the sources are built from what is
+// readable and marked as partial otherwise
+func (rp *ResourceProcessor) loadSecretSources(namespace string) *secretSources {
+	sources := &secretSources{}
+	unreadable := func(kind string, err error) {
+		if !sources.partial {
+			sources.err = err
+		}
+		sources.partial = true
+		sources.unreadable = append(sources.unreadable, kind)
+	}
+
+	if serviceAccounts, err := rp.clientset.CoreV1().ServiceAccounts(namespace).List(rp.ctx, metav1.ListOptions{}); err == nil {
+		sources.serviceAccounts = serviceAccounts.Items
+	} else {
+		unreadable("ServiceAccounts", fmt.Errorf("error getting serviceaccounts: %v", err))
+	}
+
+	if ingresses, err := rp.clientset.NetworkingV1().Ingresses(namespace).List(rp.ctx, metav1.ListOptions{}); err == nil {
+		sources.ingresses = ingresses.Items
+	} else {
+		unreadable("Ingresses", fmt.Errorf("error getting ingresses: %v", err))
+	}
+
+	if gvr, ok := rp.findResource(secretsStoreGroup, "secretproviderclasses", "v1", "v1alpha1"); ok {
+		items, err := rp.listDynamic(gvr, namespace)
+		for i := range items {
+			class := &secretProviderClass{}
+			if err = decodeUnstructured(&items[i], class); err != nil {
+				err = fmt.Errorf("error decoding SecretProviderClass %s: %v", items[i].GetName(), err)
+				break
+			}
+			sources.providerClasses = append(sources.providerClasses, class)
+		}
+		if err != nil {
+			sources.providerClasses = nil
+			unreadable("SecretProviderClasses", err)
+		}
+	}
+
+	return sources
+}
+
+// warnPartialSources warns that Secret usage misses the sources that could not be read
+func (rp *ResourceProcessor) warnPartialSources(sources *secretSources) {
+	if sources.partial {
+		rp.formatter.PrintWarning("%s are not readable; Secret usage may be incomplete", strings.Join(sources.unreadable, ", "))
+	}
+}
+
+// syncs reports whether a SecretProviderClass syncs the named Secret
+func (c *secretProviderClass) syncs(name string) bool {
+	for _, object := range c.Spec.SecretObjects {
+		if object.SecretName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// podUsages returns how a pod consumes a Secret through its ServiceAccount's
+// imagePullSecrets and the secrets-store CSI volumes syncing it
+func (s *secretSources) podUsages(pod *corev1.Pod, name string) []configUsage {
+	var usages []configUsage
+	for _, sa := range s.serviceAccounts {
+		if sa.Name != podServiceAccount(pod) {
+			continue
+		}
+		for _, pullSecret := range sa.ImagePullSecrets {
+			if pullSecret.Name == name {
+				usages = append(usages, configUsage{source: usageImagePull, serviceAccount: sa.Name})
+			}
+		}
+	}
+
+	containers := podContainers(&pod.Spec)
+	for _, volume := range pod.Spec.Volumes {
+		if volume.CSI == nil || volume.CSI.Driver != secretsStoreDriver {
+			continue
+		}
+		className := volume.CSI.VolumeAttributes["secretProviderClass"]
+		for _, class := range s.providerClasses {
+			if class.Metadata.Name == className && class.syncs(name) {
+				usages = append(usages, volumeUsages(containers, volume.Name, keySelection{}, configUsage{
+					source: usageSecretStore, volume: volume.Name, providerClass: className,
+				})...)
+			}
+		}
+	}
+	return usages
+}
+
+// secretReferrer is a non-pod object referencing a Secret
+type secretReferrer struct {
+	kind    string
+	name    string
+	details []string
+}
+
+// referrers returns the ServiceAccounts, Ingresses and SecretProviderClasses
+// referencing a Secret
+func (s *secretSources) referrers(secret *corev1.Secret) []secretReferrer {
+	var referrers []secretReferrer
+
+	for _, sa := range s.serviceAccounts {
+		var details []string
+		for _, pullSecret := range sa.ImagePullSecrets {
+			if pullSecret.Name == secret.Name {
+				details = append(details, "imagePullSecret")
+			}
+		}
+		token := secret.Type == corev1.SecretTypeServiceAccountToken &&
+			secret.Annotations[corev1.ServiceAccountNameKey] == sa.Name
+		for _, reference := range sa.Secrets {
+			token = token || reference.Name == secret.Name
+		}
+		if token {
+			details = append(details, "Token Secret")
+		}
+		if len(details) > 0 {
+			referrers = append(referrers, secretReferrer{kind: "ServiceAccount", name: sa.Name, details: details})
+		}
+	}
+
+	for _, ingress := range s.ingresses {
+		var details []string
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName != secret.Name {
+				continue
+			}
+			hosts := "all hosts"
+			if len(tls.Hosts) > 0 {
+				hosts = strings.Join(tls.Hosts, ", ")
+			}
+			details = append(details, fmt.Sprintf("TLS for: %s", hosts))
+		}
+		if len(details) > 0 {
+			referrers = append(referrers, secretReferrer{kind: "Ingress", name: ingress.Name, details: details})
+		}
+	}
+
+	for _, class := range s.providerClasses {
+		for _, object := range class.Spec.SecretObjects {
+			if object.SecretName != secret.Name {
+				continue
+			}
+			var keys []string
+			for _, data := range object.Data {
+				keys = append(keys, data.Key)
+			}
+			detail := fmt.Sprintf("Synced from provider: %s", class.Spec.Provider)
+			if len(keys) > 0 {
+				detail += fmt.Sprintf(" (keys: %s)", strings.Join(keys, ", "))
+			}
+			referrers = append(referrers, secretReferrer{kind: "SecretProviderClass", name: class.Metadata.Name, details: []string{detail}})
+		}
+	}
+
+	return referrers
+}
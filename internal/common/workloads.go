@@ -0,0 +1,245 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isPodReady reports whether the pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podsForSelector lists the pods matched by a workload's label selector
+func (rp *ResourceProcessor) podsForSelector(namespace string, selector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	pods, err := rp.clientset.CoreV1().Pods(namespace).List(rp.ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(selector),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// ownedPods keeps the pods whose controller is the given object
+func ownedPods(pods []corev1.Pod, owner metav1.Object) []corev1.Pod {
+	var owned []corev1.Pod
+	for _, pod := range pods {
+		if ref := metav1.GetControllerOf(&pod); ref != nil && ref.UID == owner.GetUID() {
+			owned = append(owned, pod)
+		}
+	}
+	return owned
+}
+
+func (rp *ResourceProcessor) ShowStatefulSetDetails(namespace string) error {
+	fmt.Println("\n[StatefulSet Layer]")
+	statefulSets, err := rp.clientset.AppsV1().StatefulSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting statefulsets: %v", err)
+	}
+
+	for i, sts := range statefulSets.Items {
+		isLast := i == len(statefulSets.Items)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "StatefulSet", sts.Name)
+		rp.formatter.Indent()
+
+		var replicas int32 = 1
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+
+		rp.formatter.PrintInfo("", "Replicas: %d/%d", sts.Status.ReadyReplicas, replicas)
+		rp.formatter.PrintInfo("", "Pod Management: %s", sts.Spec.PodManagementPolicy)
+		rp.formatter.PrintInfo("", "Update Strategy: %s", sts.Spec.UpdateStrategy.Type)
+		if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+			rp.formatter.PrintInfo("", "  Partition: %d", *ru.Partition)
+		}
+
+		// The governing Service must be headless for stable pod DNS names
+		if sts.Spec.ServiceName != "" {
+			service, err := rp.clientset.CoreV1().Services(namespace).Get(rp.ctx, sts.Spec.ServiceName, metav1.GetOptions{})
+			switch {
+			case err != nil:
+				rp.formatter.PrintStatus(fmt.Sprintf("Headless Service %s not found", sts.Spec.ServiceName), false)
+			case service.Spec.ClusterIP != corev1.ClusterIPNone:
+				rp.formatter.PrintWarning("Service %s is not headless (ClusterIP: %s)", service.Name, service.Spec.ClusterIP)
+			default:
+				rp.formatter.PrintStatus(fmt.Sprintf("Headless Service: %s", service.Name), true)
+			}
+		}
+
+		for _, claim := range sts.Spec.VolumeClaimTemplates {
+			details := []string{}
+			if storage, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+				details = append(details, fmt.Sprintf("Size: %s", storage.String()))
+			}
+			if len(claim.Spec.AccessModes) > 0 {
+				details = append(details, fmt.Sprintf("Access Modes: %v", claim.Spec.AccessModes))
+			}
+			if claim.Spec.StorageClassName != nil {
+				details = append(details, fmt.Sprintf("StorageClass: %s", *claim.Spec.StorageClassName))
+			}
+			rp.formatter.PrintRelation("VolumeClaimTemplate", claim.Name, details...)
+		}
+
+		rp.showContainers(&sts.Spec.Template.Spec)
+
+		// Show pods by ordinal so gaps in the sequence stand out
+		pods, err := rp.podsForSelector(namespace, sts.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("error getting pods for statefulset %s: %v", sts.Name, err)
+		}
+		byName := make(map[string]*corev1.Pod)
+		for i := range pods {
+			byName[pods[i].Name] = &pods[i]
+		}
+		rp.formatter.PrintInfo("", "Pods:")
+		start := int32(0)
+		if sts.Spec.Ordinals != nil {
+			start = sts.Spec.Ordinals.Start
+		}
+		for ordinal := start; ordinal < start+replicas; ordinal++ {
+			name := fmt.Sprintf("%s-%d", sts.Name, ordinal)
+			pod, ok := byName[name]
+			if !ok {
+				rp.formatter.PrintStatus(fmt.Sprintf("%d: %s missing", ordinal, name), false)
+				continue
+			}
+			status := fmt.Sprintf("%d: %s (%s", ordinal, name, pod.Status.Phase)
+			if pod.Spec.NodeName != "" {
+				status += ", Node: " + pod.Spec.NodeName
+			}
+			rp.formatter.PrintStatus(status+")", isPodReady(pod))
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+func (rp *ResourceProcessor) ShowDaemonSetDetails(namespace string) error {
+	fmt.Println("\n[DaemonSet Layer]")
+	daemonSets, err := rp.clientset.AppsV1().DaemonSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting daemonsets: %v", err)
+	}
+
+	for i, ds := range daemonSets.Items {
+		isLast := i == len(daemonSets.Items)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "DaemonSet", ds.Name)
+		rp.formatter.Indent()
+
+		rp.formatter.PrintInfo("", "Desired: %d, Current: %d, Ready: %d, Up-to-date: %d, Available: %d",
+			ds.Status.DesiredNumberScheduled, ds.Status.CurrentNumberScheduled, ds.Status.NumberReady,
+			ds.Status.UpdatedNumberScheduled, ds.Status.NumberAvailable)
+		if ds.Status.NumberMisscheduled > 0 {
+			rp.formatter.PrintWarning("Misscheduled: %d", ds.Status.NumberMisscheduled)
+		}
+		rp.formatter.PrintInfo("", "Update Strategy: %s", ds.Spec.UpdateStrategy.Type)
+		if len(ds.Spec.Template.Spec.NodeSelector) > 0 {
+			rp.formatter.PrintInfo("", "Node Selector: %v", ds.Spec.Template.Spec.NodeSelector)
+		}
+
+		rp.showContainers(&ds.Spec.Template.Spec)
+
+		pods, err := rp.podsForSelector(namespace, ds.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("error getting pods for daemonset %s: %v", ds.Name, err)
+		}
+		pods = ownedPods(pods, &ds)
+		sort.Slice(pods, func(i, j int) bool { return pods[i].Spec.NodeName < pods[j].Spec.NodeName })
+		if len(pods) > 0 {
+			rp.formatter.PrintInfo("", "Pods per Node:")
+			for _, pod := range pods {
+				node := pod.Spec.NodeName
+				if node == "" {
+					node = "<unscheduled>"
+				}
+				rp.formatter.PrintStatus(fmt.Sprintf("%s: %s (%s)", node, pod.Name, pod.Status.Phase), isPodReady(&pod))
+			}
+		}
+
+		rp.formatter.Outdent()
+	}
+
+	return nil
+}
+
+// ShowReplicaSetDetails shows active ReplicaSets. Scaled-down ReplicaSets kept by
+// a Deployment as revision history are summarized rather than listed.
+func (rp *ResourceProcessor) ShowReplicaSetDetails(namespace string) error {
+	fmt.Println("\n[ReplicaSet Layer]")
+	replicaSets, err := rp.clientset.AppsV1().ReplicaSets(namespace).List(rp.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting replicasets: %v", err)
+	}
+
+	var active []appsv1.ReplicaSet
+	history := 0
+	for _, rs := range replicaSets.Items {
+		owner := metav1.GetControllerOf(&rs)
+		if owner != nil && owner.Kind == "Deployment" && rs.Status.Replicas == 0 &&
+			rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 {
+			history++
+			continue
+		}
+		active = append(active, rs)
+	}
+
+	for i, rs := range active {
+		isLast := i == len(active)-1
+		prefix := "├──"
+		if isLast {
+			prefix = "└──"
+		}
+
+		rp.formatter.PrintResource(prefix, "ReplicaSet", rs.Name)
+		rp.formatter.Indent()
+
+		var replicas int32 = 1
+		if rs.Spec.Replicas != nil {
+			replicas = *rs.Spec.Replicas
+		}
+		rp.formatter.PrintInfo("", "Replicas: %d/%d", rs.Status.ReadyReplicas, replicas)
+		if owner := metav1.GetControllerOf(&rs); owner != nil {
+			rp.formatter.PrintInfo("", "Owner: %s/%s", owner.Kind, owner.Name)
+		}
+		if revision := rs.Annotations["deployment.kubernetes.io/revision"]; revision != "" {
+			rp.formatter.PrintInfo("", "Revision: %s", revision)
+		}
+
+		rp.showContainers(&rs.Spec.Template.Spec)
+
+		rp.formatter.Outdent()
+	}
+
+	if history > 0 {
+		rp.formatter.PrintInfo("", "Deployment history: %d scaled-down ReplicaSet(s) hidden", history)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowSecretAccess(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "shop"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "api",
+				Env: []corev1.EnvVar{{
+					Name: "DB_PASSWORD",
+					ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "db-creds"},
+						Key:                  "password",
+					}},
+				}},
+			}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "backup"},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: "monitoring"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "exporter", Namespace: "batch"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "exporter"},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-creds-reader", Namespace: "shop"},
+			Rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"secrets"},
+				ResourceNames: []string{"db-creds"}, Verbs: []string{"get"},
+			}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls-reader", Namespace: "shop"},
+			Rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"secrets"},
+				ResourceNames: []string{"web-tls"}, Verbs: []string{"get"},
+			}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-db", Namespace: "shop"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "db-creds-reader"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "backup"},
+				{Kind: rbacv1.UserKind, Name: "alice"},
+				{Kind: rbacv1.UserKind, Name: "system:serviceaccount:batch:exporter"},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "shop"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "tls-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "web"}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-lister"},
+			Rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list", "watch"},
+			}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "monitoring-secrets"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "secret-lister"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:serviceaccounts:monitoring"}},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowSecretAccess("shop", "db-creds"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Data Keys: 1",
+		"Pod/api", "Used as env var 'DB_PASSWORD' in container: api",
+		"ServiceAccount/shop/backup", "get via Role/db-creds-reader (RoleBinding/backup-db)", "➜ Pod/backup",
+		"User/alice", "Not a workload identity",
+		"ServiceAccount/batch/exporter", "➜ Pod/exporter",
+		"Group/system:serviceaccounts:monitoring", "list, watch via ClusterRole/secret-lister",
+		"Every ServiceAccount in namespace monitoring", "➜ Pod/prometheus",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "User/system:serviceaccount:") {
+		t.Errorf("Expected a ServiceAccount username to be shown as its ServiceAccount, got %s", output)
+	}
+	if strings.Contains(output, "ServiceAccount/shop/web") {
+		t.Errorf("Expected reader restricted to another Secret to be omitted, got %s", output)
+	}
+}
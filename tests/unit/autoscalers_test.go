@@ -0,0 +1,335 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestShowHPADetailsWithKEDAAndVPA(t *testing.T) {
+	replicas := int32(2)
+	utilization := int32(70)
+	queueLength := resource.MustParse("20")
+	controller := true
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					}}},
+				}},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: 2},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MaxReplicas:    5,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name:   corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+					},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "keda-hpa-worker", Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "keda.sh/v1alpha1", Kind: "ScaledObject", Name: "worker", Controller: &controller,
+				}},
+			},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "worker"},
+				MaxReplicas:    20,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ExternalMetricSourceType,
+					External: &autoscalingv2.ExternalMetricSource{
+						Metric: autoscalingv2.MetricIdentifier{Name: "s0-rabbitmq-orders"},
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &queueLength},
+					},
+				}},
+			},
+		},
+	)
+	clientset.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "keda.sh/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "scaledobjects", Kind: "ScaledObject", Namespaced: true},
+				{Name: "scaledjobs", Kind: "ScaledJob", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "autoscaling.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "verticalpodautoscalers", Kind: "VerticalPodAutoscaler", Namespaced: true},
+			},
+		},
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledobjects"}:               "ScaledObjectList",
+			{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledjobs"}:                  "ScaledJobList",
+			{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}: "VerticalPodAutoscalerList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "keda.sh/v1alpha1",
+			"kind":       "ScaledObject",
+			"metadata":   map[string]interface{}{"name": "worker", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"scaleTargetRef":  map[string]interface{}{"name": "worker"},
+				"maxReplicaCount": int64(20),
+				"triggers": []interface{}{map[string]interface{}{
+					"type":     "rabbitmq",
+					"metadata": map[string]interface{}{"queueName": "orders", "value": "20"},
+				}},
+			},
+			"status": map[string]interface{}{
+				"hpaName": "keda-hpa-worker",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+					map[string]interface{}{"type": "Active", "status": "False"},
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "keda.sh/v1alpha1",
+			"kind":       "ScaledJob",
+			"metadata":   map[string]interface{}{"name": "reports", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"jobTargetRef": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{map[string]interface{}{"name": "report", "image": "report:1"}},
+						},
+					},
+				},
+				"maxReplicaCount": int64(5),
+				"scalingStrategy": map[string]interface{}{"strategy": "accurate"},
+				"triggers": []interface{}{map[string]interface{}{
+					"type":     "aws-sqs-queue",
+					"metadata": map[string]interface{}{"queueURL": "reports"},
+				}},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling.k8s.io/v1",
+			"kind":       "VerticalPodAutoscaler",
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"targetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+			},
+			"status": map[string]interface{}{
+				"recommendation": map[string]interface{}{
+					"containerRecommendations": []interface{}{map[string]interface{}{
+						"containerName": "app",
+						"target":        map[string]interface{}{"cpu": "250m", "memory": "256Mi"},
+						"lowerBound":    map[string]interface{}{"cpu": "100m", "memory": "128Mi"},
+						"upperBound":    map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+					}},
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling.k8s.io/v1",
+			"kind":       "VerticalPodAutoscaler",
+			"metadata":   map[string]interface{}{"name": "web-memory", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"targetRef":    map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+				"updatePolicy": map[string]interface{}{"updateMode": "Initial"},
+				"resourcePolicy": map[string]interface{}{
+					"containerPolicies": []interface{}{map[string]interface{}{
+						"containerName":       "*",
+						"controlledResources": []interface{}{"memory"},
+					}},
+				},
+			},
+		}},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Managed by: ScaledObject/worker",
+		"ScaledObject/worker",
+		"Target: Deployment/worker",
+		"Min Replicas: 0 (default)",
+		"Max Replicas: 20",
+		"Trigger: rabbitmq: queueName=orders, value=20",
+		"Generated HPA: keda-hpa-worker",
+		"Ready: True",
+		"Active: False",
+		"ScaledJob/reports",
+		"Job Containers: report",
+		"Max Replicas: 5",
+		"Scaling Strategy: accurate",
+		"Trigger: aws-sqs-queue: queueURL=reports",
+		"VPA/web",
+		"Update Mode: Auto (default)",
+		"Controlled Resources: cpu, memory",
+		"Container app: cpu 250m, memory 256Mi (lower: cpu 100m, memory 128Mi; upper: cpu 1, memory 1Gi)",
+		"Current Requests: cpu 100m",
+		"vpa-hpa-conflict VerticalPodAutoscaler/web: updates cpu requests of Deployment/web, which HPA web scales on",
+		"VPA/web-memory",
+		"Update Mode: Initial",
+		"Controlled Resources: memory",
+		"Recommendations: not available yet",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "VerticalPodAutoscaler/web-memory:") {
+		t.Errorf("Expected a VPA controlling only memory not to conflict with a cpu HPA, got %s", output)
+	}
+
+	t.Run("Lint", func(t *testing.T) {
+		linter := common.NewLinter(clientset, context.Background(), &common.LintConfig{
+			Rules: map[string]common.RuleConfig{"vpa-hpa-conflict": {Severity: "warning"}},
+		})
+		linter.SetDynamicClient(dynamicClient)
+		findings, err := linter.Lint("default")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var conflicts []common.Finding
+		for _, finding := range findings {
+			if finding.ID == "vpa-hpa-conflict" {
+				conflicts = append(conflicts, finding)
+			}
+		}
+		if len(conflicts) != 1 || conflicts[0].Resource != "VerticalPodAutoscaler/web" ||
+			conflicts[0].Severity != common.SeverityWarning {
+			t.Errorf("Expected one vpa-hpa-conflict warning for VerticalPodAutoscaler/web, got %v", conflicts)
+		}
+	})
+}
+
+func TestShowHPADetailsWithoutAutoscalerCRDs(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()))
+
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if strings.Contains(output, "ScaledObject") || strings.Contains(output, "VPA") {
+		t.Errorf("Expected no KEDA or VPA output without their CRDs, got %s", output)
+	}
+}
+
+func TestShowHPADetailsWithUnreadableAutoscalers(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "api", Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					}}},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+				MaxReplicas:    5,
+			},
+		},
+	)
+	clientset.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "keda.sh/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "scaledobjects", Kind: "ScaledObject", Namespaced: true},
+				{Name: "scaledjobs", Kind: "ScaledJob", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "autoscaling.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "verticalpodautoscalers", Kind: "VerticalPodAutoscaler", Namespaced: true},
+			},
+		},
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledobjects"}:               "ScaledObjectList",
+			{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledjobs"}:                  "ScaledJobList",
+			{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}: "VerticalPodAutoscalerList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling.k8s.io/v1",
+			"kind":       "VerticalPodAutoscaler",
+			"metadata":   map[string]interface{}{"name": "api", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"targetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "api"},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling.k8s.io/v1",
+			"kind":       "VerticalPodAutoscaler",
+			"metadata":   map[string]interface{}{"name": "broken", "namespace": "default"},
+			"spec":       map[string]interface{}{"targetRef": "api"},
+		}},
+	)
+	for _, resource := range []string{"scaledobjects", "scaledjobs"} {
+		dynamicClient.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("forbidden")
+		})
+	}
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Could not list ScaledObjects: error getting scaledobjects: forbidden",
+		"Could not list ScaledJobs: error getting scaledjobs: forbidden",
+		"Could not decode VerticalPodAutoscaler broken",
+		"HPA/api",
+		"VPA/api",
+		"vpa-hpa-conflict VerticalPodAutoscaler/api: updates cpu requests of Deployment/api, which HPA api scales on",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowBatchDetails(t *testing.T) {
+	isController := true
+	started := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	finished := metav1.NewTime(started.Add(90 * time.Second))
+	jobLabels := map[string]string{"job-name": "report-28391"}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name: "report",
+			EnvFrom: []corev1.EnvFromSource{{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "warehouse"}},
+			}},
+		}},
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "data"},
+			Spec: batchv1.CronJobSpec{
+				Schedule:          "0 * * * *",
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}},
+				},
+			},
+			Status: batchv1.CronJobStatus{LastScheduleTime: &started, LastSuccessfulTime: &finished},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "report-28391", Namespace: "data", UID: "job-uid",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "report", Controller: &isController}},
+			},
+			Spec: batchv1.JobSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: jobLabels},
+				Template: corev1.PodTemplateSpec{Spec: podSpec},
+			},
+			Status: batchv1.JobStatus{
+				Succeeded: 1, StartTime: &started, CompletionTime: &finished,
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "report-28391-x2x9c", Namespace: "data", Labels: jobLabels,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "report-28391", UID: "job-uid", Controller: &isController}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "warehouse", Namespace: "data"}},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowBatchDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"CronJob/report", "Schedule: 0 * * * *", "Concurrency Policy: Forbid",
+		"Job/report-28391", "Completions: 1/1", "Duration: 1m30s",
+		"Pod/report-28391-x2x9c", "Secret/warehouse",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestShowBatchDetailsFailedJobDuration(t *testing.T) {
+	started := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	failed := metav1.NewTime(started.Add(45 * time.Second))
+	clientset := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "data"},
+			Status: batchv1.JobStatus{
+				Failed: 2, StartTime: &started,
+				Conditions: []batchv1.JobCondition{{
+					Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+					Reason: "BackoffLimitExceeded", LastTransitionTime: failed,
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowBatchDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Duration: 45s") || strings.Contains(output, "Running for") {
+		t.Errorf("Expected a failed Job to show its duration until failure, got %s", output)
+	}
+}
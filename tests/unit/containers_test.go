@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEffectivePodResources(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	resources := func(requests, limits map[corev1.ResourceName]string) corev1.ResourceRequirements {
+		result := corev1.ResourceRequirements{}
+		for name, value := range requests {
+			if result.Requests == nil {
+				result.Requests = corev1.ResourceList{}
+			}
+			result.Requests[name] = resource.MustParse(value)
+		}
+		for name, value := range limits {
+			if result.Limits == nil {
+				result.Limits = corev1.ResourceList{}
+			}
+			result.Limits[name] = resource.MustParse(value)
+		}
+		return result
+	}
+
+	// The init container runs next to the sidecar started before it, so it needs
+	// the most CPU, while the app container plus sidecar need the most memory
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{
+				Name: "proxy", Image: "envoy", RestartPolicy: &always,
+				Resources: resources(
+					map[corev1.ResourceName]string{corev1.ResourceCPU: "100m", corev1.ResourceMemory: "32Mi"},
+					map[corev1.ResourceName]string{corev1.ResourceCPU: "200m"}),
+			},
+			{
+				Name: "migrate", Image: "migrate",
+				Resources: resources(
+					map[corev1.ResourceName]string{corev1.ResourceCPU: "500m", corev1.ResourceMemory: "64Mi"}, nil),
+			},
+		},
+		Containers: []corev1.Container{{
+			Name: "app", Image: "app",
+			Resources: resources(
+				map[corev1.ResourceName]string{corev1.ResourceCPU: "200m", corev1.ResourceMemory: "128Mi"},
+				map[corev1.ResourceName]string{corev1.ResourceCPU: "400m"}),
+		}},
+		Overhead: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("16Mi"),
+		},
+	}
+
+	labels := map[string]string{"app": "web"}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: labels}, Spec: spec},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}, Spec: spec},
+			},
+		},
+	)
+
+	t.Run("ShowResourceUtilization", func(t *testing.T) {
+		metrics := common.NewResourceMetrics(clientset, common.NewFormatter())
+		output := captureOutput(func() {
+			if err := metrics.ShowResourceUtilization("default"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Requests: 650m", "Limits: 650m", "Requests: 176.00Mi", "Limits: 0B",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+
+	t.Run("ShowDeploymentDetails", func(t *testing.T) {
+		processor := common.NewResourceProcessor(clientset, context.Background())
+		output := captureOutput(func() {
+			if err := processor.ShowDeploymentDetails("default"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Sidecar Container: proxy (Image: envoy)",
+			"Init Container: migrate (Image: migrate)",
+			"Container: app (Image: app)",
+			"Effective Pod Requests: CPU 650m, Memory 176.00Mi",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+}
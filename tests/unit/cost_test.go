@@ -0,0 +1,115 @@
+package unit
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadPricingConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	config := `cpuPerHour: 0.04
+memoryGiBPerHour: 0.005
+nodePools:
+  - name: spot
+    nodeSelector:
+      pool: spot
+    cpuPerHour: 0.01
+    memoryGiBPerHour: 0.001
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("Error writing pricing config: %v", err)
+	}
+
+	pricing, err := common.LoadPricingConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pricing.Currency != "USD" {
+		t.Errorf("Expected default currency USD, got %s", pricing.Currency)
+	}
+	if len(pricing.NodePools) != 1 || pricing.NodePools[0].NodeSelector["pool"] != "spot" {
+		t.Errorf("Expected one spot node pool, got %+v", pricing.NodePools)
+	}
+}
+
+func TestEstimateNamespaceCost(t *testing.T) {
+	isController := true
+	newPod := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "shop",
+				Labels:    map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "web-5d4f", Controller: &isController},
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: node,
+				Containers: []corev1.Container{
+					{
+						Name: "web",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("1"),
+								corev1.ResourceMemory: resource.MustParse("1Gi"),
+							},
+						},
+					},
+				},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		newPod("web-5d4f-a", "node-a"),
+		newPod("web-5d4f-b", "node-spot"),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-spot", Labels: map[string]string{"pool": "spot"}}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-5d4f",
+			Namespace:       "shop",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &isController}},
+		}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+		},
+	)
+
+	pricing := &common.PricingConfig{
+		Currency:         "USD",
+		CPUPerHour:       0.04,
+		MemoryGiBPerHour: 0.005,
+		NodePools: []common.NodePoolPricing{
+			{Name: "spot", NodeSelector: map[string]string{"pool": "spot"}, CPUPerHour: 0.01, MemoryGiBPerHour: 0.001},
+		},
+	}
+
+	metrics := common.NewResourceMetrics(clientset, common.NewFormatter())
+	report, err := metrics.EstimateNamespaceCost("shop", pricing)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := (0.04 + 0.005 + 0.01 + 0.001) * 730
+	if math.Abs(report.MonthlyCost-expected) > 0.001 {
+		t.Errorf("Expected monthly cost %.2f, got %.2f", expected, report.MonthlyCost)
+	}
+	if len(report.Deployments) != 1 || report.Deployments[0].Name != "web" || report.Deployments[0].Pods != 2 {
+		t.Errorf("Expected cost attributed to Deployment web, got %+v", report.Deployments)
+	}
+	if len(report.Services) != 1 || math.Abs(report.Services[0].MonthlyCost-expected) > 0.001 {
+		t.Errorf("Expected cost attributed to Service web, got %+v", report.Services)
+	}
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const crdConfig = `resources:
+  - group: cert-manager.io
+    version: v1
+    resource: certificates
+    references:
+      - path: spec.secretName
+        kind: Secret
+      - path: "{.spec.issuerRef}"
+  - group: db.example.com
+    version: v1
+    resource: databases
+    selector: spec.selector
+    references:
+      - path: spec.credentialsSecret
+        kind: Secret
+  - group: missing.example.com
+    version: v1
+    resource: widgets
+  - group: cert-manager.io
+    version: v1
+    resource: clusterissuers
+`
+
+func TestShowCustomResources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crds.yaml")
+	if err := os.WriteFile(path, []byte(crdConfig), 0644); err != nil {
+		t.Fatalf("Error writing crd config: %v", err)
+	}
+	config, err := common.LoadCRDConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	isController := true
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "shop"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "orders-db", Namespace: "shop", UID: "sts-uid",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Database", Name: "orders", UID: "db-uid", Controller: &isController}},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "orders-db-0", Namespace: "shop", Labels: map[string]string{"db": "orders"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "orders-db", UID: "sts-uid", Controller: &isController}},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+	)
+	clientset.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "cert-manager.io/v1", APIResources: []metav1.APIResource{
+			{Name: "certificates", Kind: "Certificate", Namespaced: true},
+			{Name: "clusterissuers", Kind: "ClusterIssuer"},
+		}},
+		{GroupVersion: "db.example.com/v1", APIResources: []metav1.APIResource{{Name: "databases", Kind: "Database", Namespaced: true}}},
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}: "CertificateList",
+			{Group: "db.example.com", Version: "v1", Resource: "databases"}:     "DatabaseList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cert-manager.io/v1",
+			"kind":       "Certificate",
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"secretName": "web-tls",
+				"issuerRef":  map[string]interface{}{"name": "letsencrypt", "kind": "ClusterIssuer"},
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{
+					"type": "Ready", "status": "False", "reason": "Pending", "lastTransitionTime": "2024-01-01T00:00:00Z",
+				}},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "db.example.com/v1",
+			"kind":       "Database",
+			"metadata":   map[string]interface{}{"name": "orders", "namespace": "shop", "uid": "db-uid"},
+			"spec": map[string]interface{}{
+				"credentialsSecret": "db-creds",
+				"selector":          map[string]interface{}{"matchLabels": map[string]interface{}{"db": "orders"}},
+			},
+		}},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+	processor.SetOptions(common.ProcessorOptions{CustomResources: config})
+
+	output := captureOutput(func() {
+		if err := processor.ShowCustomResources("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"widgets (missing.example.com/v1) is not served by the cluster",
+		"clusterissuers (cert-manager.io/v1) is cluster-scoped and not mapped per namespace",
+		"Certificate/web", "API: cert-manager.io/v1", "Ready: False (Pending)",
+		"Secret/web-tls", "via spec.secretName", "Secret web-tls not found",
+		"ClusterIssuer/letsencrypt",
+		"Database/orders", "Secret/db-creds", "StatefulSet/orders-db", "Owned",
+		"Selected: 1 pods (1 ready)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Secret db-creds not found") {
+		t.Errorf("Expected existing Secret to resolve, got %s", output)
+	}
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "crds.yaml")
+		if err := os.WriteFile(path, []byte("resources:\n  - resource: widgets\n"), 0644); err != nil {
+			t.Fatalf("Error writing crd config: %v", err)
+		}
+		if _, err := common.LoadCRDConfig(path); err == nil {
+			t.Errorf("Expected an error for a resource without a version")
+		}
+	})
+}
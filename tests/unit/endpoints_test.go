@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestEndpointSlices(t *testing.T) {
+	yes, no := true, false
+	endpoint := func(pod, zone, node string, ready, serving, terminating *bool, addresses ...string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  addresses,
+			Conditions: discoveryv1.EndpointConditions{Ready: ready, Serving: serving, Terminating: terminating},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod},
+			Zone:       &zone,
+			NodeName:   &node,
+		}
+	}
+	withHints := func(e discoveryv1.Endpoint, zones ...string) discoveryv1.Endpoint {
+		e.Hints = &discoveryv1.EndpointHints{}
+		for _, zone := range zones {
+			e.Hints.ForZones = append(e.Hints.ForZones, discoveryv1.ForZone{Name: zone})
+		}
+		return e
+	}
+	portName, port, protocol := "http", int32(8080), corev1.ProtocolTCP
+	sliceLabels := map[string]string{discoveryv1.LabelServiceName: "web"}
+
+	node := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelTopologyZone: zone}}}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		node("node-1", "zone-a"),
+		node("node-2", "zone-b"),
+		node("node-4", "zone-c"),
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Name: "web-ipv4", Namespace: "shop", Labels: sliceLabels},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port, Protocol: &protocol}},
+			Endpoints: []discoveryv1.Endpoint{
+				withHints(endpoint("web-a", "zone-a", "node-1", &yes, &yes, &no, "10.0.0.1"), "zone-a"),
+				endpoint("web-b", "zone-b", "node-2", &no, &no, &no, "10.0.0.2"),
+				endpoint("web-c", "zone-a", "node-1", &no, &yes, &yes, "10.0.0.3"),
+				endpoint("web-d", "zone-a", "node-3", nil, nil, nil, "10.0.0.4"),
+				endpoint("web-e", "zone-b", "node-2", &yes, &yes, &no, "10.0.0.5"),
+			},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Name: "web-ipv6", Namespace: "shop", Labels: sliceLabels},
+			AddressType: discoveryv1.AddressTypeIPv6,
+			Endpoints: []discoveryv1.Endpoint{
+				endpoint("web-a", "zone-a", "node-1", &yes, &yes, &no, "fd00::1"),
+			},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Name: "other", Namespace: "shop", Labels: map[string]string{discoveryv1.LabelServiceName: "other"}},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{endpoint("other", "zone-a", "node-1", &yes, &yes, &no, "10.0.9.9")},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Endpoints: 3 ready, 1 not ready, 1 terminating (IPv4, IPv6)",
+		"Ports: http=8080/TCP",
+		"10.0.0.1, fd00::1 (Pod: web-a) ready, zone: zone-a, node: node-1, hints: zone-a",
+		"10.0.0.2 (Pod: web-b) not ready, zone: zone-b",
+		"10.0.0.3 (Pod: web-c) terminating, serving",
+		"10.0.0.4 (Pod: web-d) ready",
+		"Ready by zone: zone-a=2, zone-b=1, zone-c=0",
+		"No ready endpoints in zone(s) with nodes: zone-c",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "10.0.9.9") {
+		t.Errorf("Expected slices of other Services to be ignored, got %s", output)
+	}
+}
+
+func TestEndpointSlicesZoneSpread(t *testing.T) {
+	yes := true
+	endpoint := func(pod, zone string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{"10.0.0.1"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &yes},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod},
+			Zone:       &zone,
+		}
+	}
+	service := func(name string) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}}
+	}
+	slice := func(service string, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: service, Namespace: "shop",
+				Labels: map[string]string{discoveryv1.LabelServiceName: service},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   endpoints,
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		service("odd"),
+		slice("odd", endpoint("odd-1", "zone-a"), endpoint("odd-2", "zone-a"), endpoint("odd-3", "zone-b")),
+		service("skewed"),
+		slice("skewed", endpoint("skewed-1", "zone-a"), endpoint("skewed-2", "zone-a"),
+			endpoint("skewed-3", "zone-a"), endpoint("skewed-4", "zone-b")),
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if strings.Count(output, "Ready endpoints are unevenly spread across zones") != 1 {
+		t.Errorf("Expected only a skew above one endpoint to be flagged, got %s", output)
+	}
+
+	clientset.PrependReactor("list", "endpointslices", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("endpointslices is forbidden")
+	})
+	output = captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Could not fetch endpoint slices: endpointslices is forbidden") {
+		t.Errorf("Expected the EndpointSlice list error as a warning, got %s", output)
+	}
+}
@@ -0,0 +1,235 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowGatewayDetails(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "store-v1", Namespace: "shop"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "store-v2", Namespace: "shop"}},
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "gateway.networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "gatewayclasses", Kind: "GatewayClass"},
+			{Name: "gateways", Kind: "Gateway", Namespaced: true},
+			{Name: "httproutes", Kind: "HTTPRoute", Namespaced: true},
+		},
+	}}
+
+	object := func(kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+		metadata := map[string]interface{}{"name": name}
+		if namespace != "" {
+			metadata["namespace"] = namespace
+		}
+		fields["apiVersion"] = "gateway.networking.k8s.io/v1"
+		fields["kind"] = kind
+		fields["metadata"] = metadata
+		return &unstructured.Unstructured{Object: fields}
+	}
+	accepted := map[string]interface{}{
+		"type": "Accepted", "status": "True", "reason": "Accepted", "lastTransitionTime": "2024-01-01T00:00:00Z",
+	}
+	unresolved := map[string]interface{}{
+		"type": "ResolvedRefs", "status": "False", "reason": "BackendNotFound", "lastTransitionTime": "2024-01-01T00:00:00Z",
+	}
+
+	gvr := func(resource string) schema.GroupVersionResource {
+		return schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: resource}
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			gvr("gatewayclasses"): "GatewayClassList",
+			gvr("gateways"):       "GatewayList",
+			gvr("httproutes"):     "HTTPRouteList",
+		},
+		object("GatewayClass", "", "istio", map[string]interface{}{
+			"spec": map[string]interface{}{"controllerName": "istio.io/gateway-controller"},
+		}),
+		object("HTTPRoute", "shop", "store", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"parentRefs": []interface{}{map[string]interface{}{"name": "public", "sectionName": "https"}},
+				"hostnames":  []interface{}{"store.example.com"},
+				"rules": []interface{}{
+					map[string]interface{}{
+						"matches": []interface{}{map[string]interface{}{
+							"path":    map[string]interface{}{"type": "PathPrefix", "value": "/cart"},
+							"method":  "POST",
+							"headers": []interface{}{map[string]interface{}{"name": "x-canary", "value": "true"}},
+						}},
+						"backendRefs": []interface{}{
+							map[string]interface{}{"name": "store-v1", "port": int64(8080), "weight": int64(90)},
+							map[string]interface{}{"name": "store-v2", "port": int64(8080), "weight": int64(10)},
+						},
+					},
+					map[string]interface{}{
+						"backendRefs": []interface{}{map[string]interface{}{"name": "store-legacy", "port": int64(80)}},
+					},
+				},
+			},
+			"status": map[string]interface{}{
+				"parents": []interface{}{map[string]interface{}{
+					"parentRef":      map[string]interface{}{"name": "public", "sectionName": "https"},
+					"controllerName": "istio.io/gateway-controller",
+					"conditions":     []interface{}{accepted, unresolved},
+				}},
+			},
+		}),
+		object("HTTPRoute", "shop", "orphan", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"parentRefs": []interface{}{map[string]interface{}{"name": "shared", "namespace": "infra"}},
+			},
+		}),
+	)
+
+	// The fake client guesses "gatewaies" from the kind, so Gateways are created explicitly
+	gatewayObject := object("Gateway", "shop", "public", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"gatewayClassName": "istio",
+			"listeners": []interface{}{
+				map[string]interface{}{
+					"name": "https", "protocol": "HTTPS", "port": int64(443), "hostname": "*.example.com",
+					"tls": map[string]interface{}{
+						"certificateRefs": []interface{}{map[string]interface{}{"name": "wildcard-tls"}},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"value": "203.0.113.10"}},
+			"listeners": []interface{}{map[string]interface{}{"name": "https", "attachedRoutes": int64(1)}},
+		},
+	})
+	if _, err := dynamicClient.Resource(gvr("gateways")).Namespace("shop").Create(
+		context.Background(), gatewayObject, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Expected no error creating gateway, got %v", err)
+	}
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+
+	output := captureOutput(func() {
+		if err := processor.ShowGatewayDetails("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"[Gateway API Layer]",
+		"Gateway/public", "GatewayClass/istio", "Controller: istio.io/gateway-controller",
+		"Addresses: 203.0.113.10",
+		"Listener https: HTTPS *.example.com:443 (attached routes: 1)", "TLS Terminate: wildcard-tls",
+		"HTTPRoute/store", "Listeners: https", "Hostnames: store.example.com",
+		"Gateway/public (https): Accepted", "Gateway/public (https): ResolvedRefs: False (BackendNotFound)",
+		"Rule 1: path PathPrefix /cart, method POST, header x-canary=true",
+		"Service/store-v1", "Weight: 90 (90%)", "Weight: 10 (10%)",
+		"Rule 2: all requests", "Service store-legacy not found",
+		"HTTPRoute/orphan", "Parent Gateway/infra/shared not found", "No parent has reported status",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+
+	t.Run("NotInstalled", func(t *testing.T) {
+		processor := common.NewResourceProcessor(fake.NewSimpleClientset(), context.Background())
+		processor.SetDynamicClient(dynamicClient)
+		output := captureOutput(func() {
+			if err := processor.ShowGatewayDetails("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		if strings.Contains(output, "[Gateway API Layer]") {
+			t.Errorf("Expected Gateway API layer to be skipped, got %s", output)
+		}
+	})
+}
+
+func TestShowGatewayDetailsSharedGateway(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "gateway.networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "gateways", Kind: "Gateway", Namespaced: true},
+			{Name: "httproutes", Kind: "HTTPRoute", Namespaced: true},
+		},
+	}}
+	gvr := func(resource string) schema.GroupVersionResource {
+		return schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: resource}
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			gvr("gateways"):   "GatewayList",
+			gvr("httproutes"): "HTTPRouteList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/v1",
+			"kind":       "HTTPRoute",
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"parentRefs": []interface{}{map[string]interface{}{
+					"name": "shared", "namespace": "infra", "sectionName": "https",
+				}},
+				"rules": []interface{}{map[string]interface{}{
+					"backendRefs": []interface{}{map[string]interface{}{"name": "web", "port": int64(80)}},
+				}},
+			},
+		}},
+	)
+	if _, err := dynamicClient.Resource(gvr("gateways")).Namespace("infra").Create(context.Background(),
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/v1",
+			"kind":       "Gateway",
+			"metadata":   map[string]interface{}{"name": "shared", "namespace": "infra"},
+			"spec": map[string]interface{}{
+				"gatewayClassName": "istio",
+				"listeners": []interface{}{map[string]interface{}{
+					"name": "https", "protocol": "HTTPS", "port": int64(443),
+					"allowedRoutes": map[string]interface{}{"namespaces": map[string]interface{}{"from": "All"}},
+				}},
+			},
+		}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Expected no error creating gateway, got %v", err)
+	}
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+
+	output := captureOutput(func() {
+		if err := processor.ShowGatewayDetails("infra"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	for _, expected := range []string{"Gateway/shared", "HTTPRoute/shop/web", "Listeners: https", "Service/web"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the shared Gateway to list routes of other namespaces (%q), got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Service web not found") {
+		t.Errorf("Expected backends to be checked in the route's namespace, got %s", output)
+	}
+
+	output = captureOutput(func() {
+		if err := processor.ShowGatewayDetails("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "HTTPRoute/web") || !strings.Contains(output, "Gateway/infra/shared") ||
+		strings.Contains(output, "not found") {
+		t.Errorf("Expected the route to resolve its Gateway in another namespace, got %s", output)
+	}
+}
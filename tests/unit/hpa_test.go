@@ -0,0 +1,327 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestShowHPADetails(t *testing.T) {
+	utilization := int32(70)
+	currentUtilization := int32(85)
+	window := int32(600)
+	disabled := autoscalingv2.DisabledPolicySelect
+	quantity := func(value string) *resource.Quantity {
+		q := resource.MustParse(value)
+		return &q
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MaxReplicas:    10,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ContainerResourceMetricSourceType,
+						ContainerResource: &autoscalingv2.ContainerResourceMetricSource{
+							Name: corev1.ResourceCPU, Container: "app",
+							Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+						},
+					},
+					{
+						Type: autoscalingv2.ObjectMetricSourceType,
+						Object: &autoscalingv2.ObjectMetricSource{
+							DescribedObject: autoscalingv2.CrossVersionObjectReference{Kind: "Ingress", Name: "web"},
+							Metric:          autoscalingv2.MetricIdentifier{Name: "requests-per-second"},
+							Target:          autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType, Value: quantity("2k")},
+						},
+					},
+					{
+						Type: autoscalingv2.ExternalMetricSourceType,
+						External: &autoscalingv2.ExternalMetricSource{
+							Metric: autoscalingv2.MetricIdentifier{
+								Name:     "queue_messages_ready",
+								Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"queue": "orders"}},
+							},
+							Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: quantity("30")},
+						},
+					},
+				},
+				Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+					ScaleUp: &autoscalingv2.HPAScalingRules{SelectPolicy: &disabled},
+					ScaleDown: &autoscalingv2.HPAScalingRules{
+						StabilizationWindowSeconds: &window,
+						Policies: []autoscalingv2.HPAScalingPolicy{
+							{Type: autoscalingv2.PodsScalingPolicy, Value: 1, PeriodSeconds: 60},
+						},
+					},
+				},
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+				CurrentReplicas: 3,
+				DesiredReplicas: 4,
+				CurrentMetrics: []autoscalingv2.MetricStatus{
+					{
+						Type: autoscalingv2.ContainerResourceMetricSourceType,
+						ContainerResource: &autoscalingv2.ContainerResourceMetricStatus{
+							Name: corev1.ResourceCPU, Container: "app",
+							Current: autoscalingv2.MetricValueStatus{AverageUtilization: &currentUtilization, AverageValue: quantity("170m")},
+						},
+					},
+					{
+						Type: autoscalingv2.ObjectMetricSourceType,
+						Object: &autoscalingv2.ObjectMetricStatus{
+							DescribedObject: autoscalingv2.CrossVersionObjectReference{Kind: "Ingress", Name: "web"},
+							Metric:          autoscalingv2.MetricIdentifier{Name: "requests-per-second"},
+							Current:         autoscalingv2.MetricValueStatus{Value: quantity("2500")},
+						},
+					},
+				},
+				Conditions: []autoscalingv2.HorizontalPodAutoscalerCondition{
+					{Type: autoscalingv2.AbleToScale, Status: corev1.ConditionTrue, Reason: "ReadyForNewScale"},
+					{
+						Type: autoscalingv2.ScalingActive, Status: corev1.ConditionFalse, Reason: "FailedGetExternalMetric",
+						Message: "unable to get external metric queue_messages_ready",
+					},
+					{Type: autoscalingv2.ScalingLimited, Status: corev1.ConditionFalse, Reason: "DesiredWithinRange"},
+				},
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Target: Deployment/web", "Min Replicas: 1 (default)", "Max Replicas: 10",
+		"ContainerResource Metric: cpu of container app", "Target: 70% average utilization",
+		"Current: 85% average utilization (170m)",
+		"Object Metric: requests-per-second on Ingress/web", "Target: value 2k", "Current: value 2500",
+		"External Metric: queue_messages_ready (selector: queue=orders)", "Target: average value 30",
+		"Current: unavailable",
+		"Scale Up: disabled",
+		"Scale Down: stabilization 600s, policies: 1 pod per 60s",
+		"Current Replicas: 3", "Desired Replicas: 4",
+		"AbleToScale: True (ReadyForNewScale)",
+		"ScalingActive: False (FailedGetExternalMetric): unable to get external metric queue_messages_ready",
+		"ScalingLimited: False (DesiredWithinRange)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+
+	t.Run("DefaultBehavior", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+				MaxReplicas:    5,
+			},
+		})
+		processor := common.NewResourceProcessor(clientset, context.Background())
+		output := captureOutput(func() {
+			if err := processor.ShowHPADetails("default"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Scale Up: stabilization 0s, policies: 100% per 15s, 4 pods per 15s, select Max (default)",
+			"Scale Down: stabilization 300s, policies: 100% per 15s (default)",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+}
+
+func TestHPASanityChecks(t *testing.T) {
+	replicas := int32(2)
+	minReplicas := int32(3)
+	utilization := int32(70)
+	currentUtilization := int32(140)
+	averageValue := resource.MustParse("100")
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "web", Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+						}},
+						{Name: "proxy"},
+					},
+				}},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: 2},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-cpu", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    6,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name:   corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+					},
+				}},
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+				CurrentMetrics: []autoscalingv2.MetricStatus{{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricStatus{
+						Name:    corev1.ResourceCPU,
+						Current: autoscalingv2.MetricValueStatus{AverageUtilization: &currentUtilization},
+					},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-rps", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MaxReplicas:    6,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.PodsMetricSourceType,
+					Pods: &autoscalingv2.PodsMetricSource{
+						Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &averageValue},
+					},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "ghost", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "StatefulSet", Name: "ghost"},
+				MaxReplicas:    3,
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Target: Deployment/web (2/2 replicas ready)",
+		"Current: 140% average utilization, 200% of target",
+		"hpa-target-no-cpu-requests HorizontalPodAutoscaler/web-cpu: scales on CPU but container 'proxy' of Deployment/web has no CPU request",
+		"hpa-min-above-replicas HorizontalPodAutoscaler/web-cpu: minReplicas 3 is above the 2 replica(s) Deployment/web runs",
+		"hpa-multiple-scalers HorizontalPodAutoscaler/web-cpu: Deployment/web is also scaled by HPA web-rps",
+		"hpa-multiple-scalers HorizontalPodAutoscaler/web-rps: Deployment/web is also scaled by HPA web-cpu",
+		"hpa-missing-target HorizontalPodAutoscaler/ghost: scale target StatefulSet/ghost does not exist",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "container 'web' of Deployment/web has no CPU request") {
+		t.Errorf("Expected containers with requests not to be flagged, got %s", output)
+	}
+}
+
+func TestHPADefaultMetrics(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "worker"}},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "worker"},
+				MaxReplicas:    4,
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Resource Metric: cpu (default)",
+		"Target: 80% average utilization",
+		"hpa-target-no-cpu-requests HorizontalPodAutoscaler/worker: scales on CPU but container 'worker' of Deployment/worker has no CPU request",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "hpa-target-no-memory-requests") {
+		t.Errorf("Expected the default metric not to need memory requests, got %s", output)
+	}
+}
+
+func TestHPAWithUnreadableWorkloads(t *testing.T) {
+	hpa := func(name string) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MaxReplicas:    4,
+			},
+		}
+	}
+	clientset := fake.NewSimpleClientset(hpa("web"), hpa("web-extra"))
+	clientset.PrependReactor("list", "cronjobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowHPADetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Could not load scale targets, showing HPAs without target checks: error getting cronjobs: forbidden",
+		"HPA/web",
+		"Target: Deployment/web",
+		"Resource Metric: cpu (default)",
+		"hpa-multiple-scalers HorizontalPodAutoscaler/web: Deployment/web is also scaled by HPA web-extra",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "hpa-missing-target") {
+		t.Errorf("Expected no target checks without the workloads, got %s", output)
+	}
+}
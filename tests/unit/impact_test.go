@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowRolloutImpact(t *testing.T) {
+	configVolume := corev1.Volume{
+		Name: "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+		}},
+	}
+	envFrom := []corev1.EnvFromSource{{
+		ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+	}}
+	template := func(spec corev1.PodSpec) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: spec}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "shop"},
+			Data:       map[string]string{"app.yaml": "x: 1"},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Template: template(corev1.PodSpec{
+				Volumes: []corev1.Volume{configVolume},
+				Containers: []corev1.Container{{
+					Name:         "web",
+					VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/web"}},
+				}},
+			})},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Template: template(corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "api", EnvFrom: envFrom}},
+			})},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec: appsv1.StatefulSetSpec{Template: template(corev1.PodSpec{
+				Volumes: []corev1.Volume{configVolume},
+				Containers: []corev1.Container{{
+					Name:         "cache",
+					VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/cache/app.yaml", SubPath: "app.yaml"}},
+				}},
+			})},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "shop"},
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: template(corev1.PodSpec{Containers: []corev1.Container{{Name: "report", EnvFrom: envFrom}}}),
+			}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "shop"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "debug", EnvFrom: envFrom}}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Template: template(corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "unrelated"}},
+			})},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowRolloutImpact("shop", "ConfigMap", "app-config", true); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Rollout impact of ConfigMap shop/app-config",
+		"Deployment/web", "No restart required",
+		"volume config at /etc/web in container web: hot reload, mounted files update in place",
+		"Deployment/api", "Restart required",
+		"envFrom in container api: restart required, environment is read at container start",
+		"StatefulSet/cache",
+		"volume config at /etc/cache/app.yaml (subPath app.yaml) in container cache: restart required, subPath mounts are not updated",
+		"CronJob/report", "Picks up the change on its next run",
+		"Pod/debug",
+		"3 of 5 consumer(s) need a restart",
+		"[Restart Commands]",
+		"kubectl rollout restart deployment/api -n shop",
+		"kubectl rollout restart statefulset/cache -n shop",
+		"# Pod/debug has no controller; delete and recreate it",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{"Deployment/unrelated", "rollout restart deployment/web"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("Expected output not to contain %q, got %s", unexpected, output)
+		}
+	}
+
+	t.Run("UnsupportedKind", func(t *testing.T) {
+		captureOutput(func() {
+			if err := processor.ShowRolloutImpact("shop", "Service", "web", false); err == nil {
+				t.Errorf("Expected an error for an unsupported kind")
+			}
+		})
+	})
+}
+
+func TestShowRolloutImpactOtherOwners(t *testing.T) {
+	controller := true
+	envFrom := []corev1.EnvFromSource{{
+		ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+	}}
+	pod := func(name, ownerKind, ownerName string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "shop",
+				OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}},
+			},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "app", EnvFrom: envFrom}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "shop"}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "shop"}},
+		pod("migrate-x1", "Job", "migrate", corev1.PodRunning),
+		pod("legacy-a", "ReplicaSet", "legacy", corev1.PodRunning),
+		pod("legacy-b", "ReplicaSet", "legacy", corev1.PodRunning),
+		pod("checkout-7d9f-1", "Rollout", "checkout", corev1.PodRunning),
+		pod("old-x1", "Job", "old", corev1.PodSucceeded),
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowRolloutImpact("shop", "ConfigMap", "app-config", true); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Job/migrate", "ReplicaSet/legacy", "Rollout/checkout",
+		"No rollout command for Rollout; recreate its pods to pick up the change",
+		"3 of 3 consumer(s) need a restart",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{"Job/old", "kubectl rollout restart"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("Expected output not to contain %q, got %s", unexpected, output)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapKeyMatrix(t *testing.T) {
+	optional := true
+	keyRef := func(envVar, key string, optional *bool) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: envVar,
+			ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+				Key:                  key,
+				Optional:             optional,
+			}},
+		}
+	}
+	controller := true
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			Data:       map[string]string{"host": "db", "port": "5432", "legacy": "1", "app.yaml": "x: 1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+			Data:       map[string]string{"region": "eu"},
+		},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", UID: "api-uid"}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "api-7d9f", Namespace: "default", UID: "rs-uid",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "api", UID: "api-uid", Controller: &controller}},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "api-7d9f-abc", Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d9f", UID: "rs-uid", Controller: &controller}},
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+						Items: []corev1.KeyToPath{
+							{Key: "app.yaml", Path: "conf/app.yaml"},
+							{Key: "tls.yaml", Path: "conf/tls.yaml"},
+						},
+					}},
+				}},
+				InitContainers: []corev1.Container{{
+					Name: "migrate",
+					Env:  []corev1.EnvVar{keyRef("DB_HOST", "host", nil)},
+				}},
+				Containers: []corev1.Container{{
+					Name: "api",
+					Env: []corev1.EnvVar{
+						keyRef("DB_HOST", "host", nil),
+						keyRef("DB_PORT", "port", nil),
+						keyRef("DB_POOL", "pool", nil),
+						keyRef("FEATURE", "feature", &optional),
+					},
+					VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/app"}},
+				}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "worker",
+					EnvFrom: []corev1.EnvFromSource{{
+						ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}},
+					}},
+				}},
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowConfigMapUsage("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"host: Deployment/api (env DB_HOST in init container migrate), Deployment/api (env DB_HOST in container api)",
+		"port: Deployment/api (env DB_PORT in container api)",
+		"app.yaml: Deployment/api (volume config at /etc/app in container api)",
+		"legacy: not referenced",
+		"Key pool does not exist but is read by Deployment/api (env DB_POOL in container api)",
+		"Key tls.yaml does not exist but is read by Deployment/api (volume config at /etc/app in container api)",
+		"All keys: Pod/worker (envFrom in container worker)",
+		"region: all-keys consumers only",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Key feature") {
+		t.Errorf("Expected optional key reference not to be flagged, got %s", output)
+	}
+}
@@ -0,0 +1,450 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func findingIDs(findings []common.Finding) map[string]int {
+	ids := make(map[string]int)
+	for _, finding := range findings {
+		ids[finding.ID]++
+	}
+	return ids
+}
+
+func setupLintResources() *fake.Clientset {
+	replicas := int32(1)
+	utilization := int32(80)
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "lint"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "web",
+								Image: "nginx:latest",
+								Resources: corev1.ResourceRequirements{
+									Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "lint"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "lint"},
+			Spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{Name: "web"},
+				},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "lint"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MaxReplicas:    5,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name:   corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{AverageUtilization: &utilization},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+func TestLinter(t *testing.T) {
+	clientset := setupLintResources()
+
+	t.Run("DefaultRules", func(t *testing.T) {
+		linter := common.NewLinter(clientset, context.Background(), nil)
+		findings, err := linter.Lint("lint")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		ids := findingIDs(findings)
+		for _, id := range []string{"container-no-limits", "single-replica-behind-ingress", "image-latest", "hpa-target-no-cpu-requests"} {
+			if ids[id] != 1 {
+				t.Errorf("Expected one %s finding, got %d", id, ids[id])
+			}
+		}
+		if findings[0].Severity != common.SeverityError {
+			t.Errorf("Expected findings ordered by severity, got %v first", findings[0].Severity)
+		}
+	})
+
+	t.Run("ConfiguredRules", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lint.yaml")
+		config := `rules:
+  image-latest:
+    enabled: false
+  container-no-limits:
+    severity: error
+    params:
+      resources: memory
+`
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatalf("Error writing lint config: %v", err)
+		}
+		lintConfig, err := common.LoadLintConfig(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		findings, err := common.NewLinter(clientset, context.Background(), lintConfig).Lint("lint")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ids := findingIDs(findings)
+		if ids["image-latest"] != 0 {
+			t.Errorf("Expected image-latest to be disabled")
+		}
+		if ids["container-no-limits"] != 0 {
+			t.Errorf("Expected no container-no-limits findings when only memory limits are required")
+		}
+	})
+
+	t.Run("FailOnNone", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lint.yaml")
+		if err := os.WriteFile(path, []byte("failOn: none\n"), 0644); err != nil {
+			t.Fatalf("Error writing lint config: %v", err)
+		}
+		if _, err := common.LoadLintConfig(path); err != nil {
+			t.Errorf("Expected failOn: none to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("UnknownRule", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lint.yaml")
+		if err := os.WriteFile(path, []byte("rules:\n  no-such-rule: {}\n"), 0644); err != nil {
+			t.Fatalf("Error writing lint config: %v", err)
+		}
+		if _, err := common.LoadLintConfig(path); err == nil {
+			t.Errorf("Expected an error for an unknown rule")
+		}
+	})
+}
+
+func TestLintReferences(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "refs"},
+			Data:       map[string]string{"LOG_LEVEL": "info"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "refs", Labels: map[string]string{"app": "api"}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "api",
+						Image: "api:1.0",
+						Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						Env: []corev1.EnvVar{
+							{Name: "LOG_LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}, Key: "LOG_FORMAT",
+							}}},
+							{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password",
+							}}},
+						},
+					},
+				},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "refs"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "api"},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("web")}},
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "refs"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"api.example.com"}, SecretName: "api-tls"}},
+				DefaultBackend: &networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{Name: "api-v2"},
+				},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "refs"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+				MaxReplicas:    3,
+			},
+		},
+	)
+
+	findings, err := common.NewLinter(clientset, context.Background(), nil).LintReferences("refs")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ids := findingIDs(findings)
+	expected := map[string]int{
+		"ingress-missing-service":    1,
+		"ingress-missing-tls-secret": 1,
+		"service-port-mismatch":      1,
+		"missing-configmap-ref":      1,
+		"missing-secret-ref":         1,
+		"hpa-missing-target":         1,
+	}
+	for id, count := range expected {
+		if ids[id] != count {
+			t.Errorf("Expected %d %s finding(s), got %d", count, id, ids[id])
+		}
+	}
+	if ids["image-latest"] != 0 {
+		t.Errorf("Expected only reference rules to run")
+	}
+}
+
+func TestLintHPARequestsAndBatchTemplates(t *testing.T) {
+	utilization := int32(70)
+	minReplicas := int32(2)
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "lint"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "app:1.0"},
+						{Name: "proxy", Image: "proxy:1.0"},
+					},
+				}},
+			},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		deployment("implicit"),
+		deployment("container"),
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "implicit", Namespace: "lint"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "implicit"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    3,
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "implicit-extra", Namespace: "lint"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "implicit"},
+				MaxReplicas:    3,
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "container", Namespace: "lint"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "container"},
+				MaxReplicas:    3,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ContainerResourceMetricSourceType,
+					ContainerResource: &autoscalingv2.ContainerResourceMetricSource{
+						Name: corev1.ResourceCPU, Container: "app",
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+					},
+				}},
+			},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "lint"},
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "backup", Image: "backup"}},
+				}},
+			}}},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "lint"},
+			Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "migrate", Image: "migrate:latest"}},
+			}}},
+		},
+	)
+
+	findings, err := common.NewLinter(clientset, context.Background(), nil).Lint("lint")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	messages := make(map[string]bool)
+	for _, finding := range findings {
+		switch finding.ID {
+		case "hpa-target-no-cpu-requests", "image-latest", "hpa-min-above-replicas", "hpa-multiple-scalers":
+			messages[finding.Resource+": "+finding.Message] = true
+		}
+	}
+	for _, expected := range []string{
+		"HorizontalPodAutoscaler/implicit: scales on CPU but container 'app' of Deployment/implicit has no CPU request",
+		"HorizontalPodAutoscaler/implicit: scales on CPU but container 'proxy' of Deployment/implicit has no CPU request",
+		"HorizontalPodAutoscaler/container: scales on CPU but container 'app' of Deployment/container has no CPU request",
+		"CronJob/nightly: container 'backup' uses image 'backup' without a pinned tag",
+		"Job/migrate: container 'migrate' uses image 'migrate:latest' without a pinned tag",
+		"HorizontalPodAutoscaler/implicit: minReplicas 2 is above the 1 replica(s) Deployment/implicit runs",
+		"HorizontalPodAutoscaler/implicit: Deployment/implicit is also scaled by HPA implicit-extra",
+	} {
+		if !messages[expected] {
+			t.Errorf("Expected finding %q, got %v", expected, messages)
+		}
+	}
+	if messages["HorizontalPodAutoscaler/container: scales on CPU but container 'proxy' of Deployment/container has no CPU request"] {
+		t.Errorf("Expected only the container named by the ContainerResource metric to be checked")
+	}
+}
+
+func TestLintStandaloneAndCustomOwnedTemplates(t *testing.T) {
+	controller := true
+	owned := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+	spec := func(image string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}
+	}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "lint"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: spec("web:1.0")}},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "lint", OwnerReferences: owned("Deployment", "web")},
+			Spec:       appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: spec("web:1.0")}},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "lint"},
+			Spec:       appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: spec("legacy")}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abc-1", Namespace: "lint", OwnerReferences: owned("ReplicaSet", "web-abc")},
+			Spec:       spec("web:1.0"),
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-1", Namespace: "lint", OwnerReferences: owned("ReplicaSet", "legacy")},
+			Spec:       spec("legacy"),
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary-1", Namespace: "lint", OwnerReferences: owned("Rollout", "canary")},
+			Spec:       spec("canary:latest"),
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary-2", Namespace: "lint", OwnerReferences: owned("Rollout", "canary")},
+			Spec:       spec("canary:latest"),
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "etl-1", Namespace: "lint", OwnerReferences: owned("Workflow", "etl")},
+			Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: spec("etl")}},
+		},
+	)
+
+	findings, err := common.NewLinter(clientset, context.Background(), nil).Lint("lint")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var resources []string
+	for _, finding := range findings {
+		if finding.ID == "image-latest" {
+			resources = append(resources, finding.Resource)
+		}
+	}
+	expected := []string{"ReplicaSet/legacy", "Job/etl-1", "Rollout/canary"}
+	if strings.Join(resources, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected image-latest findings for %v, got %v", expected, resources)
+	}
+}
+
+func TestLintServicePortProtocols(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "ports", Labels: map[string]string{"app": "dns"}},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name: "metrics", Image: "metrics:1.0", RestartPolicy: &always,
+					Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9153}},
+				}},
+				Containers: []corev1.Container{{
+					Name: "dns", Image: "dns:1.0",
+					Ports: []corev1.ContainerPort{{Name: "dns", ContainerPort: 53, Protocol: corev1.ProtocolUDP}},
+				}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "ports"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "dns"},
+				Ports: []corev1.ServicePort{
+					{Name: "dns-udp", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromString("dns")},
+					{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("dns")},
+					{Name: "metrics", Port: 9153, TargetPort: intstr.FromString("metrics")},
+					{Name: "health", Port: 8080, TargetPort: intstr.FromInt(8080)},
+				},
+			},
+		},
+	)
+
+	findings, err := common.NewLinter(clientset, context.Background(), nil).LintReferences("ports")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var mismatches []string
+	for _, finding := range findings {
+		if finding.ID == "service-port-mismatch" {
+			mismatches = append(mismatches, finding.Message)
+		}
+	}
+	expected := "target port 'dns' of port 53/TCP matches no container port of the selected pods"
+	if len(mismatches) != 1 || mismatches[0] != expected {
+		t.Errorf("Expected only %q, got %v", expected, mismatches)
+	}
+}
+
+func TestShowBrokenReferencesListError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("secrets is forbidden")
+	})
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowBrokenReferences("default"); err != nil {
+			t.Errorf("Expected a list failure not to abort the namespace, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Could not check references") || !strings.Contains(output, "secrets is forbidden") {
+		t.Errorf("Expected a warning about the failed check, got %s", output)
+	}
+}
@@ -0,0 +1,270 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestShowMeshDetails(t *testing.T) {
+	pod := func(name, version string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "shop",
+				Labels: map[string]string{"app": "reviews", "version": version},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "reviews"}},
+		},
+		pod("reviews-v1-abc", "v1"),
+		pod("reviews-v2-def", "v2"),
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "networking.istio.io/v1beta1",
+		APIResources: []metav1.APIResource{
+			{Name: "virtualservices", Kind: "VirtualService", Namespaced: true},
+			{Name: "destinationrules", Kind: "DestinationRule", Namespaced: true},
+		},
+	}}
+
+	destination := func(host, subset string, weight int64) map[string]interface{} {
+		d := map[string]interface{}{"destination": map[string]interface{}{"host": host, "subset": subset}}
+		if weight > 0 {
+			d["weight"] = weight
+		}
+		return d
+	}
+	subset := func(name, version string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "labels": map[string]interface{}{"version": version}}
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}:  "VirtualServiceList",
+			{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}: "DestinationRuleList",
+		},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "networking.istio.io/v1beta1",
+			"kind":       "VirtualService",
+			"metadata":   map[string]interface{}{"name": "reviews", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"hosts":    []interface{}{"reviews"},
+				"gateways": []interface{}{"istio-system/public", "mesh"},
+				"http": []interface{}{
+					map[string]interface{}{
+						"name": "testers",
+						"match": []interface{}{map[string]interface{}{
+							"headers": map[string]interface{}{"end-user": map[string]interface{}{"exact": "jason"}},
+							"uri":     map[string]interface{}{"prefix": "/reviews"},
+						}},
+						"route": []interface{}{destination("reviews", "v4", 0)},
+					},
+					map[string]interface{}{
+						"route": []interface{}{
+							destination("reviews.shop.svc.cluster.local", "v1", 90),
+							destination("reviews", "v2", 10),
+						},
+					},
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "networking.istio.io/v1beta1",
+			"kind":       "DestinationRule",
+			"metadata":   map[string]interface{}{"name": "reviews", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"host":    "reviews",
+				"subsets": []interface{}{subset("v1", "v1"), subset("v2", "v2"), subset("v3", "v3")},
+			},
+		}},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+
+	t.Run("ShowMeshDetails", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowMeshDetails("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"[Service Mesh Layer]",
+			"VirtualService/reviews", "Hosts: reviews", "Gateways: istio-system/public, mesh",
+			"HTTP route 1 (testers): header end-user exact jason, uri prefix /reviews",
+			"Subset: v4", "Weight: 100%", "Subset v4 is not defined by any DestinationRule for reviews",
+			"HTTP route 2: all traffic", "Subset: v1", "Weight: 90%", "Weight: 10%",
+			"DestinationRule/reviews", "Host: reviews",
+			"Subset/v1", "Labels: version=v1", "Pods: 1 (1 ready)",
+			"Subset v3 matches no pods of Service reviews",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+
+	t.Run("ServiceLayer", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowResourceRelationships("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"VirtualService/reviews → subset v4 (100%): subset not defined",
+			"VirtualService/reviews → subset v1 (90%): 1/1 pods ready",
+			"VirtualService/reviews → subset v2 (10%): 1/1 pods ready",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+}
+
+func TestMeshRulesAcrossNamespaces(t *testing.T) {
+	newProcessor := func() (*common.ResourceProcessor, *dynamicfake.FakeDynamicClient) {
+		clientset := fake.NewSimpleClientset(
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "shop"},
+				Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "reviews"}},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "reviews-v1-abc", Namespace: "shop",
+					Labels: map[string]string{"app": "reviews", "version": "v1"},
+				},
+				Status: corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				},
+			},
+		)
+		clientset.Resources = []*metav1.APIResourceList{{
+			GroupVersion: "networking.istio.io/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "virtualservices", Kind: "VirtualService", Namespaced: true},
+				{Name: "destinationrules", Kind: "DestinationRule", Namespaced: true},
+			},
+		}}
+
+		virtualService := func(namespace, name, host, subset string) *unstructured.Unstructured {
+			return &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "networking.istio.io/v1beta1",
+				"kind":       "VirtualService",
+				"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+				"spec": map[string]interface{}{
+					"hosts": []interface{}{host},
+					"http": []interface{}{map[string]interface{}{"route": []interface{}{
+						map[string]interface{}{"destination": map[string]interface{}{"host": host, "subset": subset}},
+					}}},
+				},
+			}}
+		}
+		destinationRule := func(namespace, subset string, exportTo ...interface{}) *unstructured.Unstructured {
+			spec := map[string]interface{}{
+				"host": "reviews.shop.svc.cluster.local",
+				"subsets": []interface{}{map[string]interface{}{
+					"name": subset, "labels": map[string]interface{}{"version": subset},
+				}},
+			}
+			if len(exportTo) > 0 {
+				spec["exportTo"] = exportTo
+			}
+			return &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "networking.istio.io/v1beta1",
+				"kind":       "DestinationRule",
+				"metadata":   map[string]interface{}{"name": "reviews-" + subset, "namespace": namespace},
+				"spec":       spec,
+			}}
+		}
+
+		dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}:  "VirtualServiceList",
+				{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}: "DestinationRuleList",
+			},
+			virtualService("shop", "reviews", "reviews", "v1"),
+			virtualService("shop", "reviews-private", "reviews", "v9"),
+			virtualService("frontend", "storefront", "reviews.shop.svc.cluster.local", "v1"),
+			destinationRule("istio-system", "v1"),
+			destinationRule("other", "v9", "."),
+		)
+		processor := common.NewResourceProcessor(clientset, context.Background())
+		processor.SetDynamicClient(dynamicClient)
+		return processor, dynamicClient
+	}
+
+	t.Run("AllNamespaces", func(t *testing.T) {
+		processor, _ := newProcessor()
+		output := captureOutput(func() {
+			if err := processor.ShowMeshDetails("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if err := processor.ShowResourceRelationships("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Subset v9 is not defined by any DestinationRule for reviews",
+			"VirtualService/reviews → subset v1 (100%): 1/1 pods ready",
+			"VirtualService/frontend/storefront → subset v1 (100%): 1/1 pods ready",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+		for _, unexpected := range []string{
+			"Subset v1 is not defined",
+			"VirtualService/storefront",
+			"DestinationRule/reviews-v1",
+		} {
+			if strings.Contains(output, unexpected) {
+				t.Errorf("Expected output not to contain %q, got %s", unexpected, output)
+			}
+		}
+	})
+
+	t.Run("NamespaceFallback", func(t *testing.T) {
+		processor, dynamicClient := newProcessor()
+		dynamicClient.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetNamespace() == "" {
+				return true, nil, fmt.Errorf("forbidden")
+			}
+			return false, nil, nil
+		})
+		output := captureOutput(func() {
+			if err := processor.ShowMeshDetails("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Could not list Istio resources in all namespaces, showing shop only: error getting virtualservices: forbidden",
+			"VirtualService/reviews",
+			"Subset v1 is not defined by any DestinationRule for reviews",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+}
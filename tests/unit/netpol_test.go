@@ -0,0 +1,288 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func setupNetworkPolicyResources() *fake.Clientset {
+	pod := func(namespace, name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "app",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	service := func(name string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": name},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http"), Protocol: corev1.ProtocolTCP}},
+			},
+		}
+	}
+	port := intstr.FromInt(8080)
+
+	return fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "ingress-nginx",
+			Labels: map[string]string{"kubernetes.io/metadata.name": "ingress-nginx"},
+		}},
+		pod("ingress-nginx", "controller", map[string]string{"app.kubernetes.io/name": "ingress-nginx"}),
+		pod("shop", "web", map[string]string{"app": "web"}),
+		pod("shop", "api", map[string]string{"app": "api"}),
+		pod("shop", "debug", map[string]string{"app": "debug"}),
+		service("web"),
+		service("api"),
+		service("debug"),
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+					Name: "web", Port: networkingv1.ServiceBackendPort{Number: 80},
+				}},
+				Rules: []networkingv1.IngressRule{{IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{{
+						Path: "/api",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "api", Port: networkingv1.ServiceBackendPort{Number: 80},
+						}},
+					}}},
+				}}},
+			},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-from-ingress", Namespace: "shop"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"kubernetes.io/metadata.name": "ingress-nginx"},
+						},
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app.kubernetes.io/name": "ingress-nginx"},
+						},
+					}},
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+				}},
+			},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-deny-all", Namespace: "shop"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		},
+	)
+}
+
+func TestNetworkPolicies(t *testing.T) {
+	processor := common.NewResourceProcessor(setupNetworkPolicyResources(), context.Background())
+
+	t.Run("ShowNetworkPolicyDetails", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowNetworkPolicyDetails("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"NetworkPolicy/web-from-ingress",
+			"Ingress: allow from pods {app.kubernetes.io/name=ingress-nginx} in namespaces {kubernetes.io/metadata.name=ingress-nginx} on 8080/TCP",
+			"NetworkPolicy/api-deny-all", "Ingress: deny all",
+			"Unrestricted ingress", "Pod/debug",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+
+	t.Run("IngressReachability", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowResourceRelationships("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Ingress controller (ingress-nginx) → port 80: 1/1 pods reachable\x1b",
+			"Ingress controller (ingress-nginx) → port 80: 0/1 pods reachable, blocked by NetworkPolicy api-deny-all",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+		if count := strings.Count(output, "Ingress controller ("); count != 2 {
+			t.Errorf("Expected reachability only for the 2 Services behind the Ingress, got %d in %s", count, output)
+		}
+	})
+}
+
+func TestIngressReachabilityNamedPorts(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	port := intstr.FromInt(8443)
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name: "proxy", RestartPolicy: &always,
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8443, Protocol: corev1.ProtocolTCP}},
+				}},
+				Containers: []corev1.Container{{
+					Name:  "app",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolUDP}},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "web"},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http"), Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: networkingv1.IngressSpec{DefaultBackend: &networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{Name: "web", Port: networkingv1.ServiceBackendPort{Number: 80}},
+			}},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-https", Namespace: "shop"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Ingress controller (ingress-nginx) → port 80: 1/1 pods reachable") {
+		t.Errorf("Expected the named port to resolve to the TCP sidecar port, got %s", output)
+	}
+}
+
+func TestIngressReachabilityUnknownSourcesAndIPBlocks(t *testing.T) {
+	objects := func(controllerPods bool) *fake.Clientset {
+		web := func(name string) []runtime.Object {
+			return []runtime.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": name}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+					}}},
+					Status: corev1.PodStatus{Phase: corev1.PodRunning},
+				},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+					Spec: corev1.ServiceSpec{
+						Selector: map[string]string{"app": name},
+						Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http")}},
+					},
+				},
+			}
+		}
+		policy := func(name string, peer networkingv1.NetworkPolicyPeer) *networkingv1.NetworkPolicy {
+			return &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{peer}}},
+				},
+			}
+		}
+		backend := func(name string) networkingv1.HTTPIngressPath {
+			return networkingv1.HTTPIngressPath{Path: "/" + name, Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{Name: name, Port: networkingv1.ServiceBackendPort{Number: 80}},
+			}}
+		}
+
+		result := []runtime.Object{
+			&networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+				Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{
+						backend("labels"), backend("cidr"), backend("excepted"),
+					}},
+				}}}},
+			},
+			policy("labels", networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ingress-nginx"}},
+			}),
+			policy("cidr", networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16"}}),
+			policy("excepted", networkingv1.NetworkPolicyPeer{
+				IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+			}),
+		}
+		for _, name := range []string{"labels", "cidr", "excepted"} {
+			result = append(result, web(name)...)
+		}
+		if controllerPods {
+			result = append(result, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "controller", Namespace: "ingress-nginx",
+					Labels: map[string]string{"app": "ingress-nginx"}},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIPs: []corev1.PodIP{{IP: "10.0.1.5"}}},
+			})
+		}
+		return fake.NewSimpleClientset(result...)
+	}
+
+	t.Run("UnknownControllerPods", func(t *testing.T) {
+		processor := common.NewResourceProcessor(objects(false), context.Background())
+		output := captureOutput(func() {
+			if err := processor.ShowResourceRelationships("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		expected := "Ingress controller (ingress-nginx) → port 80: 0/1 pods reachable, 1 unknown (controller pods or their IPs are not visible)"
+		if count := strings.Count(output, expected); count != 3 {
+			t.Errorf("Expected 3 unknown reachabilities, got %d in %s", count, output)
+		}
+	})
+
+	t.Run("ControllerPodIPs", func(t *testing.T) {
+		processor := common.NewResourceProcessor(objects(true), context.Background())
+		output := captureOutput(func() {
+			if err := processor.ShowResourceRelationships("shop"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{
+			"Ingress controller (ingress-nginx) → port 80: 1/1 pods reachable\x1b",
+			"Ingress controller (ingress-nginx) → port 80: 0/1 pods reachable, blocked by NetworkPolicy excepted",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+		if count := strings.Count(output, "1/1 pods reachable"); count != 2 {
+			t.Errorf("Expected the label and CIDR policies to admit the controller, got %d in %s", count, output)
+		}
+	})
+}
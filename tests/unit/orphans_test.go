@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindOrphanedConfigObjects(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour))
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "apps", CreationTimestamp: created}
+	}
+	replicas := int32(0)
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: meta("used-by-scaled-down"), Data: map[string]string{"a": "b"}},
+		&corev1.ConfigMap{ObjectMeta: meta("stale-config"), Data: map[string]string{"key": "value"}},
+		&corev1.ConfigMap{ObjectMeta: meta("kube-root-ca.crt")},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "controller-lock", Namespace: "apps", CreationTimestamp: created,
+			Annotations: map[string]string{"control-plane.alpha.kubernetes.io/leader": `{"holderIdentity":"controller-0"}`},
+		}},
+		&corev1.ConfigMap{ObjectMeta: meta("migration-config")},
+		&batchv1.Job{
+			ObjectMeta: meta("migrate"),
+			Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "migrate",
+					EnvFrom: []corev1.EnvFromSource{{
+						ConfigMapRef: &corev1.ConfigMapEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "migration-config"},
+						},
+					}},
+				}},
+			}}},
+		},
+		&corev1.Secret{ObjectMeta: meta("web-tls")},
+		&corev1.Secret{ObjectMeta: meta("registry")},
+		&corev1.Secret{ObjectMeta: meta("old-password"), Data: map[string][]byte{"password": []byte("hunter2")}},
+		&appsv1.Deployment{
+			ObjectMeta: meta("web"),
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name: "web",
+							EnvFrom: []corev1.EnvFromSource{{
+								ConfigMapRef: &corev1.ConfigMapEnvSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "used-by-scaled-down"},
+								},
+							}},
+						}},
+					},
+				},
+			},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       meta("default"),
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: meta("web"),
+			Spec:       networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{{SecretName: "web-tls"}}},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	orphans, err := processor.FindOrphanedConfigObjects("apps")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, orphan := range orphans {
+		names[orphan.Kind+"/"+orphan.Name] = true
+	}
+	if len(orphans) != 2 || !names["ConfigMap/stale-config"] || !names["Secret/old-password"] {
+		t.Fatalf("Expected stale-config and old-password to be orphaned, got %v", names)
+	}
+
+	path := filepath.Join(t.TempDir(), "orphans.yaml")
+	if err := common.WriteOrphanManifest(path, orphans); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading manifest: %v", err)
+	}
+	if !strings.Contains(string(data), "kind: List") || !strings.Contains(string(data), "name: old-password") {
+		t.Errorf("Expected manifest to list the orphans, got %s", data)
+	}
+}
@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConnectedPodsByOwner(t *testing.T) {
+	isController := true
+	labels := map[string]string{"tier": "backend"}
+	pod := func(name, ownerKind, ownerName string, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "shop", Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &isController}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Selector: labels},
+		},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-5d4f", Namespace: "shop",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &isController}},
+		}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name: "sync-28391", Namespace: "shop",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "sync", Controller: &isController}},
+		}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "sync", Namespace: "shop"}},
+		pod("web-5d4f-a", "ReplicaSet", "web-5d4f", corev1.ConditionTrue),
+		pod("web-5d4f-b", "ReplicaSet", "web-5d4f", corev1.ConditionFalse),
+		pod("sync-28391-q8v", "Job", "sync-28391", corev1.ConditionTrue),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "shop", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Deployment/web", "Ready: 1/2 pods", "Chain: Pod → ReplicaSet/web-5d4f (2) → Deployment/web",
+		"Not Ready: web-5d4f-b (Running)",
+		"CronJob/sync", "Chain: Pod → Job/sync-28391 (1) → CronJob/sync",
+		"Pod/debug",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Pod/web-5d4f-a") {
+		t.Errorf("Expected owned pods to be collapsed, got %s", output)
+	}
+}
@@ -0,0 +1,191 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowPDBDetails(t *testing.T) {
+	controller := true
+	owned := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+	pod := func(name, app, ownerKind, ownerName string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default",
+				Labels:          map[string]string{"app": app},
+				OwnerReferences: owned(ownerKind, ownerName),
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+	pdb := func(name, app string, minAvailable, maxUnavailable *intstr.IntOrString, status policyv1.PodDisruptionBudgetStatus) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+				MinAvailable:   minAvailable,
+				MaxUnavailable: maxUnavailable,
+			},
+			Status: status,
+		}
+	}
+	one := intstr.FromInt(1)
+	two := intstr.FromInt(2)
+
+	objects := []runtime.Object{
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-abc", Namespace: "default", OwnerReferences: owned("Deployment", "web"),
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "api-xyz", Namespace: "default", OwnerReferences: owned("Deployment", "api"),
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "queue-def", Namespace: "default", OwnerReferences: owned("Deployment", "queue"),
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-ghi", Namespace: "default", OwnerReferences: owned("Deployment", "worker"),
+		}},
+		pod("web-abc-1", "web", "ReplicaSet", "web-abc", true),
+		pod("web-abc-2", "web", "ReplicaSet", "web-abc", true),
+		pod("web-abc-3", "web", "ReplicaSet", "web-abc", false),
+		pod("db-0", "db", "StatefulSet", "db", true),
+		pod("db-1", "db", "StatefulSet", "db", true),
+		pod("api-xyz-1", "api", "ReplicaSet", "api-xyz", true),
+		pod("api-xyz-2", "api", "ReplicaSet", "api-xyz", true),
+		pod("queue-def-1", "queue", "ReplicaSet", "queue-def", true),
+		pod("queue-def-2", "queue-canary", "ReplicaSet", "queue-def", true),
+		pod("worker-ghi-1", "worker", "ReplicaSet", "worker-ghi", true),
+		pod("worker-ghi-2", "worker-canary", "ReplicaSet", "worker-ghi", true),
+		pod("agent-1", "agent", "DaemonSet", "agent", true),
+		pod("agent-2", "agent", "DaemonSet", "agent", true),
+		pdb("web", "web", nil, &one, policyv1.PodDisruptionBudgetStatus{
+			DisruptionsAllowed: 1, CurrentHealthy: 2, DesiredHealthy: 2, ExpectedPods: 3,
+		}),
+		pdb("db", "db", &two, nil, policyv1.PodDisruptionBudgetStatus{
+			CurrentHealthy: 2, DesiredHealthy: 2, ExpectedPods: 2,
+		}),
+		pdb("legacy", "legacy", &one, nil, policyv1.PodDisruptionBudgetStatus{}),
+		pdb("queue", "queue", nil, &one, policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1}),
+		pdb("worker", "worker", nil, &one, policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1}),
+		pdb("worker-canary", "worker-canary", nil, &one, policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1}),
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowPDBDetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"[PodDisruptionBudget Layer]",
+		"PDB/web",
+		"Max Unavailable: 1",
+		"Allowed Disruptions: 1",
+		"Healthy Pods: 2/2 desired (3 expected)",
+		"Deployment/web",
+		"Ready: 2/3 pods",
+		"PDB/db",
+		"Min Available: 2",
+		"Allowed Disruptions: 0",
+		"StatefulSet/db",
+		"pdb-blocks-evictions PodDisruptionBudget/db: minAvailable 2 leaves no disruption for the 2 selected pod(s)",
+		"pdb-no-pods PodDisruptionBudget/legacy: selects no pods",
+		"Workloads without a PodDisruptionBudget:",
+		"workload-no-pdb Deployment/api: runs 2 pods and no PodDisruptionBudget covers them",
+		"workload-no-pdb Deployment/queue: runs 2 pods and no PodDisruptionBudget covers 1 of them",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{
+		"PodDisruptionBudget/web:",
+		"DaemonSet/agent",
+		"Deployment/web: runs",
+		"Deployment/worker: runs",
+	} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("Expected output not to contain %q, got %s", unexpected, output)
+		}
+	}
+
+	t.Run("Lint", func(t *testing.T) {
+		findings, err := common.NewLinter(clientset, context.Background(), nil).Lint("default")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ids := findingIDs(findings)
+		for id, count := range map[string]int{"pdb-no-pods": 1, "pdb-blocks-evictions": 1, "workload-no-pdb": 2} {
+			if ids[id] != count {
+				t.Errorf("Expected %d %s finding(s), got %d", count, id, ids[id])
+			}
+		}
+		for _, finding := range findings {
+			if finding.ID == "pdb-blocks-evictions" && finding.Severity != common.SeverityError {
+				t.Errorf("Expected blocking budgets to be errors, got %v", finding)
+			}
+		}
+	})
+}
+
+func TestShowPDBDetailsBlockingBudgets(t *testing.T) {
+	zero := intstr.FromInt(0)
+	all := intstr.FromString("100%")
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default", Labels: map[string]string{"app": "cache"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-eviction", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "cache"}},
+				MaxUnavailable: &zero,
+			},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "all-available", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:     &metav1.LabelSelector{},
+				MinAvailable: &all,
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowPDBDetails("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"pdb-blocks-evictions PodDisruptionBudget/no-eviction: maxUnavailable 0 allows no disruption",
+		"pdb-blocks-evictions PodDisruptionBudget/all-available: minAvailable 100% leaves no disruption for the 1 selected pod(s)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceTargetPorts(t *testing.T) {
+	pod := func(name string, ports ...corev1.ContainerPort) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Ports: ports}}},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "web"},
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromString("http"), Protocol: corev1.ProtocolTCP},
+					{Name: "admin", Port: 9000, Protocol: corev1.ProtocolTCP},
+					{Name: "grpc", Port: 50051, TargetPort: intstr.FromString("grpc"), Protocol: corev1.ProtocolTCP},
+				},
+			},
+		},
+		pod("web-1", corev1.ContainerPort{Name: "http", ContainerPort: 8080}),
+		pod("web-2", corev1.ContainerPort{Name: "http", ContainerPort: 8080}),
+		pod("web-canary", corev1.ContainerPort{Name: "http", ContainerPort: 9090}),
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Port: 80→http (8080, 9090)/TCP",
+		"targetPort http resolves differently across pods: 8080 on web-1, web-2; 9090 on web-canary",
+		"Port: 9000→9000/TCP",
+		"Port: 50051→grpc (unresolved)/TCP",
+		"targetPort grpc matches no container port on web-1, web-2, web-canary",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestServiceTargetPortsProtocolsAndSidecars(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "shop"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "dns"},
+				Ports: []corev1.ServicePort{
+					{Name: "dns-udp", Port: 53, TargetPort: intstr.FromString("dns"), Protocol: corev1.ProtocolUDP},
+					{Name: "metrics", Port: 9090, TargetPort: intstr.FromString("metrics"), Protocol: corev1.ProtocolTCP},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns-1", Namespace: "shop", Labels: map[string]string{"app": "dns"}},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name: "exporter", RestartPolicy: &always,
+					Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9153}},
+				}},
+				Containers: []corev1.Container{{
+					Name: "dns",
+					Ports: []corev1.ContainerPort{
+						{Name: "dns", ContainerPort: 5353, Protocol: corev1.ProtocolTCP},
+						{Name: "dns", ContainerPort: 5354, Protocol: corev1.ProtocolUDP},
+					},
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Port: 53→dns (5354)/UDP",
+		"Port: 9090→metrics (9153)/TCP",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "matches no container port") {
+		t.Errorf("Expected every named port to resolve, got %s", output)
+	}
+}
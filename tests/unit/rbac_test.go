@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestShowRBACDetails(t *testing.T) {
+	automount := false
+	clientset := fake.NewSimpleClientset(
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "shop"}},
+		&corev1.ServiceAccount{
+			ObjectMeta:                   metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			AutomountServiceAccountToken: &automount,
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "web"},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "shop"}},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "config-reader", Namespace: "shop"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"web-tls"}, Verbs: []string{"get"}},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "shop"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "config-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "web"}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-admin"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "web", Namespace: "shop"}},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowRBACDetails("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Pod/web", "ServiceAccount/web", "Automount Token: false",
+		"RoleBinding/web-config", "Role: Role/config-reader",
+		"ClusterRoleBinding/web-admin",
+		"configmaps: get, list", "secrets/web-tls: get", "*.*: * (cluster-wide)",
+		"Can read Secrets web-tls via Role/config-reader (RoleBinding/web-config)",
+		"Can read all Secrets via ClusterRole/cluster-admin (ClusterRoleBinding/web-admin)",
+		"Wildcard permissions via ClusterRole/cluster-admin",
+		"Pod/debug", "ServiceAccount/default", "No RBAC bindings",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestShowRBACDetailsForbiddenRoles(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "web"},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "viewer"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-viewer"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "viewer"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "web", Namespace: "shop"}},
+		},
+	)
+	for _, resource := range []string{"roles", "rolebindings"} {
+		clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("%s is forbidden", action.GetResource().Resource)
+		})
+	}
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowRBACDetails("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	for _, expected := range []string{
+		"Roles, RoleBindings are not readable; permissions may be incomplete",
+		"ClusterRoleBinding/web-viewer",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowStorageDetails(t *testing.T) {
+	isController := true
+	reclaim := corev1.PersistentVolumeReclaimDelete
+	className := "fast"
+
+	clientset := fake.NewSimpleClientset(
+		&storagev1.StorageClass{
+			ObjectMeta:    metav1.ObjectMeta{Name: "fast"},
+			Provisioner:   "ebs.csi.aws.com",
+			ReclaimPolicy: &reclaim,
+		},
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-1234"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-0abc"},
+				},
+			},
+			Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeBound},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data-db-0", Namespace: "data"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &className,
+				VolumeName:       "pvc-1234",
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Phase:    corev1.ClaimBound,
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "scratch", Namespace: "data"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "db-0", Namespace: "data",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &isController}},
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-db-0"},
+					},
+				}},
+				Containers: []corev1.Container{{
+					Name:         "postgres",
+					VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/var/lib/postgresql"}},
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowStorageDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"PersistentVolumeClaim/data-db-0", "Capacity: 10Gi (requested 10Gi)",
+		"StorageClass/fast", "Provisioner: ebs.csi.aws.com",
+		"PersistentVolume/pvc-1234", "Source: CSI ebs.csi.aws.com (vol-0abc)",
+		"StatefulSet/db", "db-0:/var/lib/postgresql (rw) in container postgres",
+		"PersistentVolumeClaim/scratch", "Status: Pending", "Not mounted by any pod",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestShowStorageDetailsEphemeralAndClasslessClaims(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "standard",
+				Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"},
+			},
+			Provisioner: "pd.csi.storage.gke.io",
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "data"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-legacy"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-scratch", Namespace: "data"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "data"},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name:         "scratch",
+					VolumeSource: corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{}},
+				}},
+				Containers: []corev1.Container{{
+					Name:         "app",
+					VolumeMounts: []corev1.VolumeMount{{Name: "scratch", MountPath: "/scratch"}},
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowStorageDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "worker:/scratch (rw) in container app") {
+		t.Errorf("Expected the ephemeral volume claim to be used by its pod, got %s", output)
+	}
+	if strings.Count(output, "StorageClass/standard") != 1 {
+		t.Errorf("Expected the default class only for the pending claim, got %s", output)
+	}
+}
@@ -0,0 +1,152 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// generateCertificate returns a PEM encoded self-signed certificate
+func generateCertificate(t *testing.T, dnsNames []string, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestParseCertificate(t *testing.T) {
+	notAfter := time.Now().Add(10 * 24 * time.Hour)
+	cert, err := common.ParseCertificate(generateCertificate(t, []string{"*.example.com", "example.com"}, notAfter))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(cert.Subject, "*.example.com") {
+		t.Errorf("Expected subject to contain the common name, got %s", cert.Subject)
+	}
+
+	for host, covered := range map[string]bool{
+		"example.com":       true,
+		"api.example.com":   true,
+		"a.b.example.com":   false,
+		"api.other.com":     false,
+		"API.EXAMPLE.COM.":  true,
+		"notexample.com":    false,
+		".example.com":      false,
+		"www.example.com.":  true,
+		"example.com.evil":  false,
+		"api.example.com.x": false,
+	} {
+		if cert.Covers(host) != covered {
+			t.Errorf("Expected Covers(%q) to be %v", host, covered)
+		}
+	}
+
+	if !cert.ExpiresWithin(time.Now(), 30*24*time.Hour) {
+		t.Errorf("Expected certificate to expire within 30 days")
+	}
+	if cert.ExpiresWithin(time.Now(), 24*time.Hour) {
+		t.Errorf("Expected certificate not to expire within a day")
+	}
+
+	if _, err := common.ParseCertificate([]byte("not a certificate")); err == nil {
+		t.Errorf("Expected an error for invalid PEM data")
+	}
+}
+
+func TestIngressCertificateInspection(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop-tls", Namespace: "shop"},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey: generateCertificate(t, []string{"shop.example.com"}, time.Now().Add(5*24*time.Hour)),
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{
+					Hosts:      []string{"shop.example.com", "api.example.com"},
+					SecretName: "shop-tls",
+				}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "SANs: shop.example.com") {
+		t.Errorf("Expected SANs in output, got %s", output)
+	}
+	if !strings.Contains(output, "⚠ Expires:") {
+		t.Errorf("Expected an expiry warning, got %s", output)
+	}
+	if !strings.Contains(output, "Host api.example.com not covered by certificate") {
+		t.Errorf("Expected uncovered host to be flagged, got %s", output)
+	}
+	if strings.Contains(output, "Host shop.example.com not covered") {
+		t.Errorf("Expected shop.example.com to be covered, got %s", output)
+	}
+}
+
+func TestIngressCertificateExpiryWindow(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop-tls", Namespace: "shop"},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey: generateCertificate(t, []string{"shop.example.com"}, time.Now().Add(5*24*time.Hour)),
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"shop.example.com"}, SecretName: "shop-tls"}},
+			},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	window := time.Duration(0)
+	processor.SetOptions(common.ProcessorOptions{CertExpiryWindow: &window})
+
+	output := captureOutput(func() {
+		if err := processor.ShowResourceRelationships("shop"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if strings.Contains(output, "⚠ Expires:") || !strings.Contains(output, "✓ Expires:") {
+		t.Errorf("Expected a zero window to flag only expired certificates, got %s", output)
+	}
+}
@@ -0,0 +1,227 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSecretUsageByContainerType(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	secretVolume := func(name string) corev1.Volume {
+		return corev1.Volume{
+			Name:         name,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "db-creds"}},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "default"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{secretVolume("creds"), secretVolume("unused")},
+				InitContainers: []corev1.Container{
+					{
+						Name: "migrate",
+						Env: []corev1.EnvVar{{
+							Name: "DB_PASSWORD",
+							ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "db-creds"},
+								Key:                  "password",
+							}},
+						}},
+					},
+					{
+						Name: "proxy", RestartPolicy: &always,
+						VolumeMounts: []corev1.VolumeMount{{Name: "creds", MountPath: "/etc/proxy/password", SubPath: "password"}},
+					},
+				},
+				Containers: []corev1.Container{{
+					Name:         "api",
+					VolumeMounts: []corev1.VolumeMount{{Name: "creds", MountPath: "/etc/creds"}},
+				}},
+				EphemeralContainers: []corev1.EphemeralContainer{{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name: "debugger",
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db-creds"}},
+						}},
+					},
+				}},
+			},
+		},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	output := captureOutput(func() {
+		if err := processor.ShowSecretUsage("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Used as env var 'DB_PASSWORD' in init container: migrate",
+		"Mounted as volume: creds at /etc/proxy/password (subPath password) in sidecar container: proxy",
+		"Mounted as volume: creds at /etc/creds in container: api",
+		"Mounted as volume: unused (not mounted by any container)",
+		"Used in envFrom by ephemeral container: debugger",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestSecretUsageOutsideContainers(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vault-sync", Namespace: "default"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "builder-token", Namespace: "default",
+				Annotations: map[string]string{corev1.ServiceAccountNameKey: "builder"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "default"}},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}, SecretName: "web-tls"}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-1", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "builder",
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+				Volumes: []corev1.Volume{
+					{
+						Name: "bundle",
+						VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{{
+								Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "web-tls"}},
+							}},
+						}},
+					},
+					{
+						Name: "vault",
+						VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+							Driver:               "secrets-store.csi.k8s.io",
+							VolumeAttributes:     map[string]string{"secretProviderClass": "vault-db"},
+							NodePublishSecretRef: &corev1.LocalObjectReference{Name: "registry"},
+						}},
+					},
+				},
+				Containers: []corev1.Container{{
+					Name: "build",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "bundle", MountPath: "/etc/bundle"},
+						{Name: "vault", MountPath: "/mnt/secrets"},
+					},
+				}},
+			},
+		},
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "secrets-store.csi.x-k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "secretproviderclasses", Kind: "SecretProviderClass", Namespaced: true}},
+	}}
+
+	gvr := schema.GroupVersionResource{Group: "secrets-store.csi.x-k8s.io", Version: "v1", Resource: "secretproviderclasses"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "SecretProviderClassList"},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "secrets-store.csi.x-k8s.io/v1",
+			"kind":       "SecretProviderClass",
+			"metadata":   map[string]interface{}{"name": "vault-db", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"provider": "vault",
+				"secretObjects": []interface{}{map[string]interface{}{
+					"secretName": "vault-sync",
+					"data":       []interface{}{map[string]interface{}{"key": "password", "objectName": "db-password"}},
+				}},
+			},
+		}},
+	)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+	output := captureOutput(func() {
+		if err := processor.ShowSecretUsage("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	for _, expected := range []string{
+		"Used as imagePullSecret",
+		"Used as imagePullSecret via ServiceAccount: builder",
+		"Used as node publish secret of CSI volume: vault",
+		"Synced by SecretProviderClass vault-db into volume: vault at /mnt/secrets in container: build",
+		"Mounted as projected volume: bundle at /etc/bundle in container: build",
+		"ServiceAccount/builder", "imagePullSecret", "Token Secret",
+		"Ingress/web", "TLS for: web.example.com",
+		"SecretProviderClass/vault-db", "Synced from provider: vault (keys: password)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+}
+
+func TestSecretUsageWithUnreadableSources(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"}},
+	)
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "secrets-store.csi.x-k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "secretproviderclasses", Kind: "SecretProviderClass", Namespaced: true}},
+	}}
+	forbidden := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("%s is forbidden", action.GetResource().Resource)
+	}
+	clientset.PrependReactor("list", "serviceaccounts", forbidden)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "secrets-store.csi.x-k8s.io", Version: "v1", Resource: "secretproviderclasses"}: "SecretProviderClassList",
+		})
+	dynamicClient.PrependReactor("list", "secretproviderclasses", forbidden)
+
+	processor := common.NewResourceProcessor(clientset, context.Background())
+	processor.SetDynamicClient(dynamicClient)
+	output := captureOutput(func() {
+		if err := processor.ShowSecretUsage("default"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	for _, expected := range []string{
+		"ServiceAccounts, SecretProviderClasses are not readable; Secret usage may be incomplete",
+		"Secret/registry",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+
+	if _, err := processor.FindOrphanedConfigObjects("default"); err == nil ||
+		!strings.Contains(err.Error(), "serviceaccounts is forbidden") {
+		t.Errorf("Expected orphan detection to refuse incomplete sources, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mbergo/k8s-microlens/internal/common"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadLayers(t *testing.T) {
+	replicas := int32(2)
+	isController := true
+	labels := map[string]string{"app": "db"}
+
+	readyPod := func(name string, owner metav1.OwnerReference, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "data", Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	stsOwner := metav1.OwnerReference{Kind: "StatefulSet", Name: "db", UID: types.UID("sts-uid"), Controller: &isController}
+	dsOwner := metav1.OwnerReference{Kind: "DaemonSet", Name: "agent", UID: types.UID("ds-uid"), Controller: &isController}
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data", UID: "sts-uid"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:    &replicas,
+				ServiceName: "db",
+				Selector:    &metav1.LabelSelector{MatchLabels: labels},
+				Template:    corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+					ObjectMeta: metav1.ObjectMeta{Name: "data"},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+						},
+					},
+				}},
+			},
+			Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"},
+			Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+		},
+		readyPod("db-0", stsOwner, "node-a"),
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "data", UID: "ds-uid"},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+			},
+			Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberReady: 1, NumberMisscheduled: 1},
+		},
+		readyPod("agent-x7k2p", dsOwner, "node-b"),
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	t.Run("ShowStatefulSetDetails", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowStatefulSetDetails("data"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{"Headless Service: db", "VolumeClaimTemplate/data", "Size: 10Gi", "0: db-0 (Running", "1: db-1 missing"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+	})
+
+	t.Run("ShowDaemonSetDetails", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := processor.ShowDaemonSetDetails("data"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		for _, expected := range []string{"Desired: 2", "Misscheduled: 1", "node-b: agent-x7k2p"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got %s", expected, output)
+			}
+		}
+		if strings.Contains(output, "db-0") {
+			t.Errorf("Expected pods of other controllers to be excluded, got %s", output)
+		}
+	})
+
+	t.Run("ShowReplicaSetDetails", func(t *testing.T) {
+		if err := processor.ShowReplicaSetDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestStatefulSetOrdinalsStart(t *testing.T) {
+	replicas := int32(2)
+	labels := map[string]string{"app": "queue"}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "queue", Namespace: "data"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: &replicas,
+				Ordinals: &appsv1.StatefulSetOrdinals{Start: 5},
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "queue-5", Namespace: "data", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "queue-6", Namespace: "data", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+	processor := common.NewResourceProcessor(clientset, context.Background())
+
+	output := captureOutput(func() {
+		if err := processor.ShowStatefulSetDetails("data"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	for _, expected := range []string{"5: queue-5 (Running", "6: queue-6 (Running"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "missing") {
+		t.Errorf("Expected no missing ordinals, got %s", output)
+	}
+}